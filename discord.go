@@ -39,3 +39,12 @@ func (Service) AsSessionRestorer() cchat.SessionRestorer {
 func (Service) AsConfigurator() cchat.Configurator {
 	return config.World
 }
+
+// Focuser is implemented by the sessions. Frontends should tell every session
+// whether their window is focused: new messages in the opened channel aren't
+// marked as read while it isn't; they are once it's focused again.
+type Focuser interface {
+	SetFocused(focused bool)
+}
+
+var _ Focuser = (*session.Session)(nil)
@@ -3,13 +3,18 @@ module github.com/diamondburned/cchat-discord
 go 1.14
 
 require (
+	github.com/alecthomas/chroma v0.10.0
 	github.com/diamondburned/arikawa/v2 v2.0.0-20210106050916-771591e5eb65
 	github.com/diamondburned/cchat v0.3.17
 	github.com/diamondburned/ningen/v2 v2.0.0-20210106052055-9da2a0102d49
 	github.com/dustin/go-humanize v1.0.0
 	github.com/go-test/deep v1.0.7
+	github.com/gorilla/websocket v1.4.2
 	github.com/lithammer/fuzzysearch v1.1.1
+	github.com/mattn/go-runewidth v0.0.16
 	github.com/pkg/errors v0.9.1
 	github.com/skratchdot/open-golang v0.0.0-20200116055534-eef842397966
 	github.com/yuin/goldmark v1.1.30
+	golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2
+	rsc.io/qr v0.2.0
 )
@@ -18,5 +18,6 @@ func FirstStageAuthenticators() []cchat.Authenticator {
 		NewTokenAuthenticator(),
 		NewLoginAuthenticator(),
 		NewDiscordLogin(),
+		NewQRAuthenticator(),
 	}
 }
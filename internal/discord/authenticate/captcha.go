@@ -0,0 +1,88 @@
+package authenticate
+
+import (
+	"strings"
+
+	"github.com/diamondburned/arikawa/v2/api"
+	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat/text"
+)
+
+// ErrNeedsCaptcha is returned from Authenticator if Discord wants a captcha to
+// be solved before logging in.
+type ErrNeedsCaptcha struct {
+	params loginParams
+
+	Sitekey string
+	Service string
+	Rqdata  string
+	Rqtoken string
+}
+
+func (err ErrNeedsCaptcha) Error() string {
+	return "Captcha required"
+}
+
+func (err ErrNeedsCaptcha) NextStage() []cchat.Authenticator {
+	return []cchat.Authenticator{
+		NewCaptchaAuthenticator(err),
+	}
+}
+
+// CaptchaAuthenticator is a second stage authenticator that retries the login
+// with the solved captcha key.
+type CaptchaAuthenticator struct {
+	client  *api.Client
+	captcha ErrNeedsCaptcha
+}
+
+func NewCaptchaAuthenticator(captcha ErrNeedsCaptcha) *CaptchaAuthenticator {
+	return &CaptchaAuthenticator{
+		client:  api.NewClient(""),
+		captcha: captcha,
+	}
+}
+
+func (auth *CaptchaAuthenticator) Name() text.Rich {
+	return text.Plain("Captcha")
+}
+
+func (auth *CaptchaAuthenticator) Description() text.Rich {
+	var builder strings.Builder
+	builder.WriteString("Discord wants a captcha solved before logging in. ")
+	builder.WriteString("Solve the ")
+
+	if auth.captcha.Service != "" {
+		builder.WriteString(auth.captcha.Service + " ")
+	}
+
+	builder.WriteString("challenge with the site key ")
+	builder.WriteString(auth.captcha.Sitekey)
+
+	if auth.captcha.Rqdata != "" {
+		builder.WriteString(" and the rqdata ")
+		builder.WriteString(auth.captcha.Rqdata)
+	}
+
+	builder.WriteString(", then paste the response key.")
+
+	return text.Plain(builder.String())
+}
+
+func (auth *CaptchaAuthenticator) AuthenticateForm() []cchat.AuthenticateEntry {
+	return []cchat.AuthenticateEntry{
+		{Name: "Captcha Key", Description: "Response key of the solved captcha"},
+	}
+}
+
+func (auth *CaptchaAuthenticator) Authenticate(v []string) (cchat.Session, cchat.AuthenticateError) {
+	if len(v) != 1 || v[0] == "" {
+		return nil, cchat.WrapAuthenticateError(ErrMalformed)
+	}
+
+	params := auth.captcha.params
+	params.CaptchaKey = v[0]
+	params.CaptchaRqtoken = auth.captcha.Rqtoken
+
+	return login(auth.client, params)
+}
@@ -0,0 +1,71 @@
+package authenticate
+
+import (
+	"github.com/diamondburned/arikawa/v2/api"
+	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat/text"
+)
+
+// verifyLocationCode is the error code Discord returns when the login comes
+// from a new location that has to be confirmed over email.
+const verifyLocationCode = "ACCOUNT_LOGIN_VERIFICATION_EMAIL"
+
+// ErrVerifyLocation is returned from Authenticator if the login has to be
+// confirmed through the link that Discord sent over email.
+type ErrVerifyLocation struct {
+	params  loginParams
+	message string
+}
+
+func (err ErrVerifyLocation) Error() string {
+	if err.message != "" {
+		return err.message
+	}
+	return "New login location detected, please check your email"
+}
+
+func (err ErrVerifyLocation) NextStage() []cchat.Authenticator {
+	return []cchat.Authenticator{
+		NewVerifyLocationAuthenticator(err.params),
+	}
+}
+
+// VerifyLocationAuthenticator is a second stage authenticator that retries the
+// login once the user has confirmed the new location.
+type VerifyLocationAuthenticator struct {
+	client *api.Client
+	params loginParams
+}
+
+func NewVerifyLocationAuthenticator(params loginParams) *VerifyLocationAuthenticator {
+	// The captcha key can only be used once.
+	params.CaptchaKey = ""
+	params.CaptchaRqtoken = ""
+
+	return &VerifyLocationAuthenticator{
+		client: api.NewClient(""),
+		params: params,
+	}
+}
+
+func (auth *VerifyLocationAuthenticator) Name() text.Rich {
+	return text.Plain("Verify Location")
+}
+
+func (auth *VerifyLocationAuthenticator) Description() text.Rich {
+	return text.Plain(
+		"Discord sent an email to confirm this login location. " +
+			"Open the link in the email, then continue.",
+	)
+}
+
+// AuthenticateForm returns an empty slice.
+func (auth *VerifyLocationAuthenticator) AuthenticateForm() []cchat.AuthenticateEntry {
+	return []cchat.AuthenticateEntry{}
+}
+
+// Authenticate retries the login. It returns ErrVerifyLocation again if the
+// location isn't confirmed yet.
+func (auth *VerifyLocationAuthenticator) Authenticate([]string) (cchat.Session, cchat.AuthenticateError) {
+	return login(auth.client, auth.params)
+}
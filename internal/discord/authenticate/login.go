@@ -1,7 +1,10 @@
 package authenticate
 
 import (
+	"encoding/json"
+
 	"github.com/diamondburned/arikawa/v2/api"
+	"github.com/diamondburned/arikawa/v2/utils/httputil"
 	"github.com/diamondburned/cchat"
 	"github.com/diamondburned/cchat-discord/internal/discord/session"
 	"github.com/diamondburned/cchat-discord/internal/discord/state"
@@ -42,16 +45,39 @@ func (a *LoginAuthenticator) Authenticate(form []string) (cchat.Session, cchat.A
 	}
 
 	// Try to login without TOTP
-	l, err := a.client.Login(form[0], form[1])
+	return login(a.client, loginParams{Email: form[0], Password: form[1]})
+}
+
+// loginParams is the body of the login request. The captcha fields are only
+// filled after Discord asks for a captcha.
+type loginParams struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+
+	CaptchaKey     string `json:"captcha_key,omitempty"`
+	CaptchaRqtoken string `json:"captcha_rqtoken,omitempty"`
+}
+
+// login sends the login request and turns the response into either a session
+// or an error with the next stage, such as 2FA or a captcha.
+func login(client *api.Client, params loginParams) (cchat.Session, cchat.AuthenticateError) {
+	var l *api.LoginResponse
+
+	err := client.RequestJSON(&l, "POST", api.EndpointLogin, httputil.WithJSONBody(params))
 	if err != nil {
-		return nil, cchat.WrapAuthenticateError(errors.Wrap(err, "failed to login"))
+		return nil, loginError(err, params)
 	}
 
 	if l.MFA {
 		return nil, &ErrNeeds2FA{loginResp: l}
 	}
 
-	i, err := state.NewFromToken(l.Token)
+	return newSession(l.Token)
+}
+
+// newSession makes a session from the token that a login stage returned.
+func newSession(token string) (cchat.Session, cchat.AuthenticateError) {
+	i, err := state.NewFromToken(token)
 	if err != nil {
 		return nil, cchat.WrapAuthenticateError(errors.Wrap(err, "failed to use token"))
 	}
@@ -63,3 +89,54 @@ func (a *LoginAuthenticator) Authenticate(form []string) (cchat.Session, cchat.A
 
 	return s, nil
 }
+
+// loginErrorBody is the part of a failed login response that says what Discord
+// wants before it lets the user in.
+type loginErrorBody struct {
+	CaptchaKey     []string `json:"captcha_key"`
+	CaptchaSitekey string   `json:"captcha_sitekey"`
+	CaptchaService string   `json:"captcha_service"`
+	CaptchaRqdata  string   `json:"captcha_rqdata"`
+	CaptchaRqtoken string   `json:"captcha_rqtoken"`
+
+	Errors map[string]struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"_errors"`
+	} `json:"errors"`
+}
+
+// loginError parses err into an ErrNeedsCaptcha or ErrVerifyLocation if
+// possible. Other errors are wrapped as-is.
+func loginError(err error, params loginParams) cchat.AuthenticateError {
+	var httpErr *httputil.HTTPError
+	if !errors.As(err, &httpErr) || len(httpErr.Body) == 0 {
+		return cchat.WrapAuthenticateError(errors.Wrap(err, "failed to login"))
+	}
+
+	var body loginErrorBody
+	if json.Unmarshal(httpErr.Body, &body) != nil {
+		return cchat.WrapAuthenticateError(errors.Wrap(err, "failed to login"))
+	}
+
+	if body.CaptchaSitekey != "" {
+		return &ErrNeedsCaptcha{
+			params:  params,
+			Sitekey: body.CaptchaSitekey,
+			Service: body.CaptchaService,
+			Rqdata:  body.CaptchaRqdata,
+			Rqtoken: body.CaptchaRqtoken,
+		}
+	}
+
+	for _, field := range body.Errors {
+		for _, fieldErr := range field.Errors {
+			if fieldErr.Code == verifyLocationCode {
+				return &ErrVerifyLocation{params: params, message: fieldErr.Message}
+			}
+		}
+	}
+
+	return cchat.WrapAuthenticateError(errors.Wrap(err, "failed to login"))
+}
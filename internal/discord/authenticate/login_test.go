@@ -0,0 +1,85 @@
+package authenticate
+
+import (
+	"testing"
+
+	"github.com/diamondburned/arikawa/v2/api"
+	"github.com/diamondburned/arikawa/v2/utils/httputil"
+	"github.com/pkg/errors"
+)
+
+func TestLoginError(t *testing.T) {
+	params := loginParams{Email: "a@b.c", Password: "hunter2"}
+
+	var tests = []struct {
+		name   string
+		err    error
+		expect string
+	}{{
+		name: "captcha",
+		err: &httputil.HTTPError{Status: 400, Body: []byte(`{
+			"captcha_key": ["captcha-required"],
+			"captcha_sitekey": "site",
+			"captcha_service": "hcaptcha"
+		}`)},
+		expect: "captcha",
+	}, {
+		name: "location",
+		err: &httputil.HTTPError{Status: 400, Body: []byte(`{
+			"code": 50035,
+			"errors": {"login": {"_errors": [{
+				"code": "ACCOUNT_LOGIN_VERIFICATION_EMAIL",
+				"message": "New login location detected, please check your e-mail."
+			}]}}
+		}`)},
+		expect: "location",
+	}, {
+		name: "invalid login",
+		err: &httputil.HTTPError{Status: 400, Body: []byte(`{
+			"code": 50035,
+			"errors": {"login": {"_errors": [{"code": "INVALID_LOGIN"}]}}
+		}`)},
+	}, {
+		name: "request",
+		err:  errors.New("network down"),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got string
+
+			switch err := loginError(test.err, params).(type) {
+			case *ErrNeedsCaptcha:
+				got = "captcha"
+				if err.Sitekey != "site" || err.params != params {
+					t.Fatalf("Unexpected captcha error: %#v", err)
+				}
+			case *ErrVerifyLocation:
+				got = "location"
+				if err.params != params {
+					t.Fatalf("Unexpected location error: %#v", err)
+				}
+			}
+
+			if got != test.expect {
+				t.Fatalf("Expected %q, got %q", test.expect, got)
+			}
+		})
+	}
+}
+
+func Test2FAStages(t *testing.T) {
+	for _, sms := range []bool{false, true} {
+		stages := ErrNeeds2FA{loginResp: &api.LoginResponse{MFA: true, SMS: sms}}.NextStage()
+
+		var hasSMS bool
+		for _, stage := range stages {
+			_, ok := stage.(*SMSAuthenticator)
+			hasSMS = hasSMS || ok
+		}
+
+		if hasSMS != sms {
+			t.Fatalf("SMS stage offered = %v, expected %v", hasSMS, sms)
+		}
+	}
+}
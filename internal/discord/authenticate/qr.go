@@ -0,0 +1,192 @@
+package authenticate
+
+import (
+	"context"
+	"encoding/base64"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-discord/internal/discord/authenticate/remoteauth"
+	"github.com/diamondburned/cchat-discord/internal/discord/session"
+	"github.com/diamondburned/cchat-discord/internal/discord/state"
+	"github.com/diamondburned/cchat-discord/internal/segments/inline"
+	"github.com/diamondburned/cchat-discord/internal/segments/link"
+	"github.com/diamondburned/cchat-discord/internal/segments/segutil"
+	"github.com/diamondburned/cchat/text"
+	"github.com/diamondburned/cchat/utils/empty"
+	"github.com/pkg/errors"
+	"rsc.io/qr"
+)
+
+// QRAuthenticator is a first stage authenticator that allows the user to log
+// in by scanning a QR code with the Discord mobile app.
+type QRAuthenticator struct{}
+
+func NewQRAuthenticator() QRAuthenticator {
+	return QRAuthenticator{}
+}
+
+func (QRAuthenticator) Name() text.Rich {
+	return text.Plain("QR Code")
+}
+
+func (QRAuthenticator) Description() text.Rich {
+	return text.Plain("Log in by scanning a QR code with the Discord app.")
+}
+
+// AuthenticateForm returns an empty slice.
+func (QRAuthenticator) AuthenticateForm() []cchat.AuthenticateEntry {
+	return []cchat.AuthenticateEntry{}
+}
+
+// Authenticate connects to the remote authentication gateway and returns an
+// error with the next stage that shows the QR code.
+func (QRAuthenticator) Authenticate([]string) (cchat.Session, cchat.AuthenticateError) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	c, err := remoteauth.Dial(ctx)
+	if err != nil {
+		return nil, cchat.WrapAuthenticateError(errors.Wrap(err, "failed to start QR login"))
+	}
+
+	code, err := qr.Encode(c.URL(), qr.L)
+	if err != nil {
+		c.Close()
+		return nil, cchat.WrapAuthenticateError(errors.Wrap(err, "failed to make QR code"))
+	}
+
+	return nil, &ErrScanQR{client: c, code: code}
+}
+
+// ErrScanQR is returned from QRAuthenticator once the QR code is ready.
+type ErrScanQR struct {
+	client *remoteauth.Client
+	code   *qr.Code
+}
+
+func (err ErrScanQR) Error() string {
+	return "Scan the QR code with the Discord app"
+}
+
+func (err ErrScanQR) NextStage() []cchat.Authenticator {
+	return []cchat.Authenticator{
+		&QRScanAuthenticator{client: err.client, code: err.code},
+	}
+}
+
+// QRScanAuthenticator is the second stage of QRAuthenticator. Its description
+// has the QR code, and Authenticate waits for the user to confirm the login on
+// their phone.
+type QRScanAuthenticator struct {
+	client *remoteauth.Client
+	code   *qr.Code
+}
+
+func (auth *QRScanAuthenticator) Name() text.Rich {
+	return text.Plain("Scan QR Code")
+}
+
+// Description returns the QR code drawn with block characters, followed by an
+// image of it and the link that it encodes.
+func (auth *QRScanAuthenticator) Description() text.Rich {
+	var rich text.Rich
+
+	segutil.Write(&rich, "Scan this with the Discord app, then continue:\n\n")
+
+	start, end := segutil.Write(&rich, RenderQR(auth.code))
+	segutil.Add(&rich, inline.NewSegment(start, end, text.AttributeMonospace))
+
+	segutil.Add(&rich, imageSegment{
+		start: len(rich.Content),
+		url:   "data:image/png;base64," + base64.StdEncoding.EncodeToString(auth.code.PNG()),
+		size:  auth.code.Size * auth.code.Scale,
+	})
+
+	segutil.Write(&rich, "\n")
+
+	url := auth.client.URL()
+	start, end = segutil.Write(&rich, url)
+	segutil.Add(&rich, link.NewSegment(start, end, url))
+
+	return rich
+}
+
+// AuthenticateForm returns an empty slice.
+func (auth *QRScanAuthenticator) AuthenticateForm() []cchat.AuthenticateEntry {
+	return []cchat.AuthenticateEntry{}
+}
+
+// Authenticate blocks until the login is confirmed on the phone or the QR code
+// expires.
+func (auth *QRScanAuthenticator) Authenticate([]string) (cchat.Session, cchat.AuthenticateError) {
+	token, err := auth.client.Wait(context.Background(), func(u remoteauth.User) {
+		log.Printf("[Discord] QR code scanned by %s#%s\n", u.Username, u.Discriminator)
+	})
+	if err != nil {
+		return nil, cchat.WrapAuthenticateError(errors.Wrap(err, "QR login failed"))
+	}
+
+	i, err := state.NewFromToken(token)
+	if err != nil {
+		return nil, cchat.WrapAuthenticateError(errors.Wrap(err, "failed to use token"))
+	}
+
+	s, err := session.NewFromInstance(i)
+	if err != nil {
+		return nil, cchat.WrapAuthenticateError(errors.Wrap(err, "failed to make a session"))
+	}
+
+	return s, nil
+}
+
+// RenderQR draws the QR code with half block characters, so that each line of
+// text has two rows of the code. A quiet zone is added around it.
+func RenderQR(code *qr.Code) string {
+	const quiet = 2
+
+	black := func(x, y int) bool {
+		x -= quiet
+		y -= quiet
+		return x >= 0 && y >= 0 && x < code.Size && y < code.Size && code.Black(x, y)
+	}
+
+	var builder strings.Builder
+	size := code.Size + quiet*2
+
+	for y := 0; y < size; y += 2 {
+		for x := 0; x < size; x++ {
+			// Light modules are drawn as blocks for dark themes.
+			switch top, bottom := !black(x, y), !black(x, y+1) && y+1 < size; {
+			case top && bottom:
+				builder.WriteRune('█')
+			case top:
+				builder.WriteRune('▀')
+			case bottom:
+				builder.WriteRune('▄')
+			default:
+				builder.WriteRune(' ')
+			}
+		}
+		builder.WriteByte('\n')
+	}
+
+	return builder.String()
+}
+
+type imageSegment struct {
+	empty.TextSegment
+	start int
+	url   string
+	size  int
+}
+
+func (seg imageSegment) Bounds() (start, end int) { return seg.start, seg.start }
+
+func (seg imageSegment) AsImager() text.Imager { return seg }
+
+func (seg imageSegment) Image() string         { return seg.url }
+func (seg imageSegment) ImageSize() (w, h int) { return seg.size, seg.size }
+func (seg imageSegment) ImageText() string     { return "QR code" }
@@ -0,0 +1,355 @@
+// Package remoteauth implements Discord's remote authentication gateway, which
+// allows logging in by scanning a QR code with the mobile app.
+package remoteauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+)
+
+var (
+	// GatewayURL is the remote authentication gateway.
+	GatewayURL = "wss://remote-auth-gateway.discord.gg/?v=2"
+	// LoginURL is the endpoint that exchanges the ticket for the token.
+	LoginURL = "https://discord.com/api/v9/users/@me/remote-auth/login"
+	// Origin is the origin header that the gateway requires.
+	Origin = "https://discord.com"
+)
+
+// QRPrefix is the URL prefix of the QR code's content.
+const QRPrefix = "https://discord.com/ra/"
+
+// ErrCancelled is returned if the user cancelled the login on their phone.
+var ErrCancelled = errors.New("login cancelled on the phone")
+
+// User is the user that scanned the QR code.
+type User struct {
+	ID            discord.UserID
+	Discriminator string
+	Avatar        string
+	Username      string
+}
+
+type payload struct {
+	Op string `json:"op"`
+
+	HeartbeatInterval int `json:"heartbeat_interval,omitempty"`
+	TimeoutMS         int `json:"timeout_ms,omitempty"`
+
+	EncodedPublicKey     string `json:"encoded_public_key,omitempty"`
+	EncryptedNonce       string `json:"encrypted_nonce,omitempty"`
+	Proof                string `json:"proof,omitempty"`
+	Fingerprint          string `json:"fingerprint,omitempty"`
+	EncryptedUserPayload string `json:"encrypted_user_payload,omitempty"`
+	Ticket               string `json:"ticket,omitempty"`
+	EncryptedToken       string `json:"encrypted_token,omitempty"`
+}
+
+// Client is a connection to the remote authentication gateway.
+type Client struct {
+	conn *websocket.Conn
+	key  *rsa.PrivateKey
+
+	fingerprint string
+	deadline    time.Time
+
+	writeMu sync.Mutex
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// Dial connects to the gateway and does the key exchange. It returns once the
+// QR code is ready.
+func Dial(ctx context.Context) (*Client, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate key")
+	}
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, GatewayURL, http.Header{
+		"Origin": {Origin},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect")
+	}
+
+	c := &Client{
+		conn: conn,
+		key:  key,
+		stop: make(chan struct{}),
+	}
+
+	if err := c.handshake(); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Client) handshake() error {
+	hello, err := c.read()
+	if err != nil {
+		return err
+	}
+
+	if hello.Op != "hello" {
+		return errors.Errorf("expected hello, got %q", hello.Op)
+	}
+
+	if hello.TimeoutMS > 0 {
+		c.deadline = time.Now().Add(time.Duration(hello.TimeoutMS) * time.Millisecond)
+		c.conn.SetReadDeadline(c.deadline)
+	}
+
+	go c.heartbeat(time.Duration(hello.HeartbeatInterval) * time.Millisecond)
+
+	pub, err := x509.MarshalPKIXPublicKey(&c.key.PublicKey)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode public key")
+	}
+
+	err = c.write(payload{
+		Op:               "init",
+		EncodedPublicKey: base64.StdEncoding.EncodeToString(pub),
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		p, err := c.read()
+		if err != nil {
+			return err
+		}
+
+		switch p.Op {
+		case "nonce_proof":
+			nonce, err := c.decrypt(p.EncryptedNonce)
+			if err != nil {
+				return errors.Wrap(err, "failed to decrypt nonce")
+			}
+
+			sum := sha256.Sum256(nonce)
+
+			err = c.write(payload{
+				Op:    "nonce_proof",
+				Proof: base64.RawURLEncoding.EncodeToString(sum[:]),
+			})
+			if err != nil {
+				return err
+			}
+
+		case "pending_remote_init":
+			c.fingerprint = p.Fingerprint
+			return nil
+
+		case "heartbeat_ack":
+			// ok
+
+		default:
+			return errors.Errorf("unexpected %q during handshake", p.Op)
+		}
+	}
+}
+
+// Fingerprint returns the fingerprint of this login attempt.
+func (c *Client) Fingerprint() string { return c.fingerprint }
+
+// URL returns the URL to be encoded into the QR code.
+func (c *Client) URL() string { return QRPrefix + c.fingerprint }
+
+// Deadline returns the time that the QR code expires. It is zero if the
+// gateway didn't say.
+func (c *Client) Deadline() time.Time { return c.deadline }
+
+// Wait waits for the user to scan the QR code and confirm the login, then
+// returns the token. The onScan callback is called with the user that scanned
+// the code, if it's not nil. The connection is closed afterwards.
+func (c *Client) Wait(ctx context.Context, onScan func(User)) (string, error) {
+	defer c.Close()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.Close()
+		case <-c.stop:
+		}
+	}()
+
+	for {
+		p, err := c.read()
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			return "", err
+		}
+
+		switch p.Op {
+		case "pending_ticket":
+			b, err := c.decrypt(p.EncryptedUserPayload)
+			if err != nil {
+				return "", errors.Wrap(err, "failed to decrypt user")
+			}
+
+			if u, err := parseUser(string(b)); err == nil && onScan != nil {
+				onScan(u)
+			}
+
+		case "pending_login":
+			encrypted, err := exchangeTicket(ctx, p.Ticket)
+			if err != nil {
+				return "", err
+			}
+
+			return c.decryptToken(encrypted)
+
+		case "finish":
+			// Older versions of the gateway send the token directly.
+			return c.decryptToken(p.EncryptedToken)
+
+		case "cancel":
+			return "", ErrCancelled
+
+		case "heartbeat_ack":
+			// ok
+
+		default:
+			log.Println("[Discord] Unknown remote auth op:", p.Op)
+		}
+	}
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	var err error
+	c.once.Do(func() {
+		close(c.stop)
+		err = c.conn.Close()
+	})
+	return err
+}
+
+func (c *Client) heartbeat(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			if err := c.write(payload{Op: "heartbeat"}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *Client) read() (payload, error) {
+	var p payload
+	if err := c.conn.ReadJSON(&p); err != nil {
+		return p, errors.Wrap(err, "failed to read from the remote auth gateway")
+	}
+	return p, nil
+}
+
+func (c *Client) write(p payload) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.conn.WriteJSON(p); err != nil {
+		return errors.Wrap(err, "failed to write to the remote auth gateway")
+	}
+	return nil
+}
+
+func (c *Client) decrypt(b64 string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return nil, err
+	}
+
+	return rsa.DecryptOAEP(sha256.New(), rand.Reader, c.key, b, nil)
+}
+
+func (c *Client) decryptToken(b64 string) (string, error) {
+	token, err := c.decrypt(b64)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decrypt token")
+	}
+	return string(token), nil
+}
+
+// parseUser parses the user payload, which is formatted as
+// "id:discriminator:avatar:username".
+func parseUser(s string) (User, error) {
+	parts := strings.SplitN(s, ":", 4)
+	if len(parts) != 4 {
+		return User{}, errors.New("malformed user payload")
+	}
+
+	id, err := discord.ParseSnowflake(parts[0])
+	if err != nil {
+		return User{}, errors.Wrap(err, "invalid user ID")
+	}
+
+	return User{
+		ID:            discord.UserID(id),
+		Discriminator: parts[1],
+		Avatar:        parts[2],
+		Username:      parts[3],
+	}, nil
+}
+
+func exchangeTicket(ctx context.Context, ticket string) (string, error) {
+	body, err := json.Marshal(map[string]string{"ticket": ticket})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest("POST", LoginURL, bytes.NewReader(body))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create request")
+	}
+
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to exchange ticket")
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		return "", errors.Errorf("failed to exchange ticket: unexpected status %s", r.Status)
+	}
+
+	var resp payload
+	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+		return "", errors.Wrap(err, "failed to decode ticket response")
+	}
+
+	return resp.EncryptedToken, nil
+}
@@ -0,0 +1,119 @@
+package remoteauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestLogin(t *testing.T) {
+	const token = "the token"
+
+	var pub *rsa.PublicKey
+
+	encrypt := func(s string) string {
+		b, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, []byte(s), nil)
+		if err != nil {
+			t.Error("Failed to encrypt:", err)
+		}
+		return base64.StdEncoding.EncodeToString(b)
+	}
+
+	login := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Ticket string }
+		json.NewDecoder(r.Body).Decode(&body)
+
+		if body.Ticket != "ticket" {
+			t.Errorf("Unexpected ticket %q", body.Ticket)
+		}
+
+		json.NewEncoder(w).Encode(payload{EncryptedToken: encrypt(token)})
+	}))
+	defer login.Close()
+
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != Origin {
+			t.Error("Unexpected origin", r.Header.Get("Origin"))
+		}
+
+		upgrader := websocket.Upgrader{
+			CheckOrigin: func(*http.Request) bool { return true },
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error("Failed to upgrade:", err)
+			return
+		}
+		defer conn.Close()
+
+		var p payload
+
+		conn.WriteJSON(payload{Op: "hello", HeartbeatInterval: 41250, TimeoutMS: 60000})
+
+		conn.ReadJSON(&p)
+		der, _ := base64.StdEncoding.DecodeString(p.EncodedPublicKey)
+		key, err := x509.ParsePKIXPublicKey(der)
+		if err != nil {
+			t.Error("Invalid public key:", err)
+			return
+		}
+		pub = key.(*rsa.PublicKey)
+
+		conn.WriteJSON(payload{Op: "nonce_proof", EncryptedNonce: encrypt("nonce")})
+
+		conn.ReadJSON(&p)
+		sum := sha256.Sum256([]byte("nonce"))
+		if p.Proof != base64.RawURLEncoding.EncodeToString(sum[:]) {
+			t.Error("Invalid proof", p.Proof)
+			return
+		}
+
+		conn.WriteJSON(payload{Op: "pending_remote_init", Fingerprint: "abc"})
+		conn.WriteJSON(payload{
+			Op:                   "pending_ticket",
+			EncryptedUserPayload: encrypt("1234:0001:avatar:user"),
+		})
+		conn.WriteJSON(payload{Op: "pending_login", Ticket: "ticket"})
+
+		conn.ReadJSON(&p)
+	}))
+	defer gateway.Close()
+
+	GatewayURL = "ws" + strings.TrimPrefix(gateway.URL, "http")
+	LoginURL = login.URL
+
+	c, err := Dial(context.Background())
+	if err != nil {
+		t.Fatal("Failed to dial:", err)
+	}
+
+	if url := c.URL(); url != QRPrefix+"abc" {
+		t.Fatalf("Unexpected URL %q", url)
+	}
+
+	var scanned User
+
+	got, err := c.Wait(context.Background(), func(u User) { scanned = u })
+	if err != nil {
+		t.Fatal("Failed to wait:", err)
+	}
+
+	if got != token {
+		t.Fatalf("Unexpected token %q", got)
+	}
+
+	if scanned.ID != 1234 || scanned.Username != "user" {
+		t.Fatalf("Unexpected user %#v", scanned)
+	}
+}
@@ -0,0 +1,128 @@
+package authenticate
+
+import (
+	"github.com/diamondburned/arikawa/v2/api"
+	"github.com/diamondburned/arikawa/v2/utils/httputil"
+	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat/text"
+	"github.com/pkg/errors"
+)
+
+var (
+	EndpointSMSSend = api.EndpointAuth + "mfa/sms/send"
+	EndpointSMS     = api.EndpointAuth + "mfa/sms"
+)
+
+// SMSAuthenticator is a second stage authenticator that asks Discord to text
+// the 2FA code to the user's phone.
+type SMSAuthenticator struct {
+	client *api.Client
+	ticket string
+}
+
+func NewSMSAuthenticator(ticket string) *SMSAuthenticator {
+	return &SMSAuthenticator{
+		client: api.NewClient(""),
+		ticket: ticket,
+	}
+}
+
+func (auth *SMSAuthenticator) Name() text.Rich {
+	return text.Plain("SMS")
+}
+
+func (auth *SMSAuthenticator) Description() text.Rich {
+	return text.Plain("Send the 2FA code to your phone.")
+}
+
+// AuthenticateForm returns an empty slice.
+func (auth *SMSAuthenticator) AuthenticateForm() []cchat.AuthenticateEntry {
+	return []cchat.AuthenticateEntry{}
+}
+
+// Authenticate sends the SMS and returns ErrSMSSent.
+func (auth *SMSAuthenticator) Authenticate([]string) (cchat.Session, cchat.AuthenticateError) {
+	var param struct {
+		Ticket string `json:"ticket"`
+	}
+	param.Ticket = auth.ticket
+
+	var resp struct {
+		Phone string `json:"phone"`
+	}
+
+	err := auth.client.RequestJSON(&resp, "POST", EndpointSMSSend, httputil.WithJSONBody(param))
+	if err != nil {
+		return nil, cchat.WrapAuthenticateError(errors.Wrap(err, "failed to send SMS"))
+	}
+
+	return nil, &ErrSMSSent{ticket: auth.ticket, phone: resp.Phone}
+}
+
+// ErrSMSSent is returned from SMSAuthenticator once the code is sent.
+type ErrSMSSent struct {
+	ticket string
+	phone  string
+}
+
+func (err ErrSMSSent) Error() string {
+	if err.phone != "" {
+		return "Code sent to " + err.phone
+	}
+	return "Code sent to your phone"
+}
+
+func (err ErrSMSSent) NextStage() []cchat.Authenticator {
+	return []cchat.Authenticator{
+		NewSMSCodeAuthenticator(err.ticket),
+	}
+}
+
+// SMSCodeAuthenticator takes the code that SMSAuthenticator had sent.
+type SMSCodeAuthenticator struct {
+	client *api.Client
+	ticket string
+}
+
+func NewSMSCodeAuthenticator(ticket string) *SMSCodeAuthenticator {
+	return &SMSCodeAuthenticator{
+		client: api.NewClient(""),
+		ticket: ticket,
+	}
+}
+
+func (auth *SMSCodeAuthenticator) Name() text.Rich {
+	return text.Plain("SMS Code")
+}
+
+func (auth *SMSCodeAuthenticator) Description() text.Rich {
+	return text.Plain("Enter the code sent to your phone.")
+}
+
+func (auth *SMSCodeAuthenticator) AuthenticateForm() []cchat.AuthenticateEntry {
+	return []cchat.AuthenticateEntry{
+		{Name: "Code", Description: "6-digit code"},
+	}
+}
+
+func (auth *SMSCodeAuthenticator) Authenticate(v []string) (cchat.Session, cchat.AuthenticateError) {
+	if len(v) != 1 {
+		return nil, cchat.WrapAuthenticateError(ErrMalformed)
+	}
+
+	var param struct {
+		Code   string `json:"code"`
+		Ticket string `json:"ticket"`
+	}
+	param.Code = v[0]
+	param.Ticket = auth.ticket
+
+	var l *api.LoginResponse
+
+	err := auth.client.RequestJSON(&l, "POST", EndpointSMS, httputil.WithJSONBody(param))
+	if err != nil {
+		return nil, cchat.WrapAuthenticateError(errors.Wrap(err, "failed to login with SMS"))
+	}
+
+	return newSession(l.Token)
+}
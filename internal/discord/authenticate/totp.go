@@ -1,6 +1,8 @@
 package authenticate
 
 import (
+	"strings"
+
 	"github.com/diamondburned/arikawa/v2/api"
 	"github.com/diamondburned/cchat"
 	"github.com/diamondburned/cchat-discord/internal/discord/session"
@@ -19,10 +21,19 @@ func (err ErrNeeds2FA) Error() string {
 	return "Two-Factor Authentication token required"
 }
 
+// NextStage returns the TOTP and backup code authenticators, as well as the SMS
+// authenticator if the user has a phone number set up for 2FA.
 func (err ErrNeeds2FA) NextStage() []cchat.Authenticator {
-	return []cchat.Authenticator{
+	stages := []cchat.Authenticator{
 		NewTOTPAuthenticator(err.loginResp.Ticket),
+		NewBackupCodeAuthenticator(err.loginResp.Ticket),
+	}
+
+	if err.loginResp.SMS {
+		stages = append(stages, NewSMSAuthenticator(err.loginResp.Ticket))
 	}
+
+	return stages
 }
 
 // TOTPAuthenticator is a second stage authenticator that follows the normal
@@ -75,3 +86,49 @@ func (auth *TOTPAuthenticator) Authenticate(v []string) (cchat.Session, cchat.Au
 
 	return s, nil
 }
+
+// BackupCodeAuthenticator is a second stage authenticator that takes one of
+// the backup codes instead of a TOTP token, for users that lost their
+// authenticator app.
+type BackupCodeAuthenticator struct {
+	client *api.Client
+	ticket string
+}
+
+func NewBackupCodeAuthenticator(ticket string) *BackupCodeAuthenticator {
+	return &BackupCodeAuthenticator{
+		client: api.NewClient(""),
+		ticket: ticket,
+	}
+}
+
+func (auth *BackupCodeAuthenticator) Name() text.Rich {
+	return text.Plain("Backup Code")
+}
+
+func (auth *BackupCodeAuthenticator) Description() text.Rich {
+	return text.Plain("Enter one of your 2FA backup codes.")
+}
+
+func (auth *BackupCodeAuthenticator) AuthenticateForm() []cchat.AuthenticateEntry {
+	return []cchat.AuthenticateEntry{
+		{Name: "Backup Code", Description: "8-character code"},
+	}
+}
+
+func (auth *BackupCodeAuthenticator) Authenticate(v []string) (cchat.Session, cchat.AuthenticateError) {
+	if len(v) != 1 {
+		return nil, cchat.WrapAuthenticateError(ErrMalformed)
+	}
+
+	// Backup codes are sent through the same endpoint, but Discord doesn't
+	// accept the dash that they're displayed with.
+	code := strings.ReplaceAll(strings.TrimSpace(v[0]), "-", "")
+
+	l, err := auth.client.TOTP(code, auth.ticket)
+	if err != nil {
+		return nil, cchat.WrapAuthenticateError(errors.Wrap(err, "failed to login with backup code"))
+	}
+
+	return newSession(l.Token)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/diamondburned/cchat"
 	"github.com/diamondburned/cchat-discord/internal/discord/channel"
 	"github.com/diamondburned/cchat-discord/internal/discord/state"
+	"github.com/diamondburned/cchat-discord/internal/discord/voice"
 	"github.com/diamondburned/cchat/text"
 	"github.com/diamondburned/cchat/utils/empty"
 	"github.com/pkg/errors"
@@ -14,10 +15,10 @@ import (
 
 func ChGuildCheck(chType discord.ChannelType) bool {
 	switch chType {
-	case discord.GuildCategory, discord.GuildText:
+	case discord.GuildCategory, discord.GuildText, discord.GuildNews, discord.GuildStore:
 		return true
 	default:
-		return false
+		return voice.IsVoice(chType)
 	}
 }
 
@@ -62,6 +63,7 @@ type Category struct {
 	id      discord.ChannelID
 	guildID discord.GuildID
 	state   *state.Instance
+	voices  voice.Channels
 }
 
 func New(s *state.Instance, ch discord.Channel) cchat.Server {
@@ -103,16 +105,36 @@ func (c *Category) Servers(container cchat.ServersContainer) error {
 		return chs[i].Position < chs[j].Position
 	})
 
+	// Sort so that text channels are before voice channels.
+	sort.SliceStable(chs, func(i, j int) bool {
+		return !voice.IsVoice(chs[i].Type) && voice.IsVoice(chs[j].Type)
+	})
+
 	var chv = make([]cchat.Server, len(chs))
+	var voices []*voice.Channel
+
 	for i := range chs {
+		if voice.IsVoice(chs[i].Type) {
+			v := voice.New(c.state, chs[i], container)
+			voices = append(voices, v)
+			chv[i] = v
+			continue
+		}
+
 		c, err := channel.New(c.state, chs[i])
 		if err != nil {
+			for _, v := range voices {
+				v.Close()
+			}
+
 			return errors.Wrapf(err, "Failed to make channel %s: %v", chs[i].Name, err)
 		}
 
 		chv[i] = c
 	}
 
+	c.voices.Replace(voices)
+
 	container.SetServers(chv)
 	return nil
 }
@@ -74,9 +74,19 @@ func (ch Channel) AsMessenger() cchat.Messenger {
 		return nil
 	}
 
+	// Store channels don't have any messages.
+	if c, err := ch.Self(); err == nil && c.Type == discord.GuildStore {
+		return nil
+	}
+
 	return message.New(ch.Channel)
 }
 
+// AsConfigurator returns the configuration overrides for this channel.
+func (ch Channel) AsConfigurator() cchat.Configurator {
+	return ch.State.Overrides.Channel(ch.Channel.ID)
+}
+
 func (ch Channel) AsIconer() cchat.Iconer {
 	// Guild channels never have an icon.
 	if ch.GuildID.IsValid() {
@@ -41,3 +41,14 @@ func (args Arguments) At(i int) (name, flag string) {
 
 	return fis[1], fis[0]
 }
+
+// Options returns the options of the argument at i if it's written as a list of
+// options, such as "on|off". Nil is returned otherwise.
+func (args Arguments) Options(i int) []string {
+	name, _ := args.At(i)
+	if !strings.Contains(name, "|") {
+		return nil
+	}
+
+	return strings.Split(name, "|")
+}
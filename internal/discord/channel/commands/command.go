@@ -2,15 +2,15 @@ package commands
 
 import (
 	"bytes"
-
-	"github.com/diamondburned/cchat-discord/internal/discord/channel/shared"
 )
 
+// Command is a command that runs against the target it was created for, such as
+// a channel or a guild.
 type Command struct {
 	Name    string
 	Args    Arguments
 	Desc    string
-	RunFunc func(shared.Channel, []string) ([]byte, error) // words[1:]
+	RunFunc func([]string) ([]byte, error) // words[1:]
 }
 
 func (cmd Command) writeHelp(builder *bytes.Buffer) {
@@ -7,10 +7,16 @@ import (
 	"fmt"
 	"io/ioutil"
 	"strings"
+	"time"
 
 	"github.com/diamondburned/arikawa/v2/bot/extras/arguments"
 	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/cchat"
 	"github.com/diamondburned/cchat-discord/internal/discord/channel/shared"
+	"github.com/diamondburned/cchat-discord/internal/discord/search"
+	"github.com/diamondburned/cchat-discord/internal/discord/state"
+	"github.com/diamondburned/cchat-discord/internal/segments"
+	"github.com/diamondburned/cchat/text"
 	"github.com/pkg/errors"
 )
 
@@ -29,7 +35,7 @@ func (cmds Commands) Help() []byte {
 
 // Run runs a command with the given words. It errors out if the command is not
 // found.
-func (cmds Commands) Run(ch shared.Channel, words []string) ([]byte, error) {
+func (cmds Commands) Run(words []string) ([]byte, error) {
 	if words[0] == "help" {
 		return cmds.Help(), nil
 	}
@@ -39,7 +45,7 @@ func (cmds Commands) Run(ch shared.Channel, words []string) ([]byte, error) {
 		return nil, fmt.Errorf("unknown command %q, refer to help", words[0])
 	}
 
-	return cmd.RunFunc(ch, words[1:])
+	return cmd.RunFunc(words[1:])
 }
 
 // FindExact finds the exact command. It returns a pointer to the command
@@ -74,121 +80,421 @@ func (cmds Commands) Find(name string) []Command {
 	return found
 }
 
-// World is a list of commands.
-var World = Commands{
-	{
-		Name: "send-embed",
-		Args: Arguments{"-t title", "-c color", "description"},
-		Desc: "Send a basic embed to the current channel",
-		RunFunc: func(ch shared.Channel, argv []string) ([]byte, error) {
-			var embed discord.Embed
-			var color uint // no Uint32Var
-
-			fs := flag.NewFlagSet("send-embed", 0)
-			fs.SetOutput(ioutil.Discard)
-			fs.StringVar(&embed.Title, "t", "", "Embed title")
-			fs.UintVar(&color, "c", 0xFFFFFF, "Embed color")
-
-			if err := fs.Parse(argv); err != nil {
-				return nil, err
-			}
-
-			embed.Description = fs.Arg(0)
-			embed.Color = discord.Color(color)
-
-			m, err := ch.State.SendEmbed(ch.ID, embed)
-			if err != nil {
-				return nil, errors.Wrap(err, "failed to send embed")
-			}
+// Complete completes the command names for the first word. For the other
+// words, arguments with options, such as "on|off", are completed with their
+// options, and other arguments are completed with completeArg, which may be nil.
+func (cmds Commands) Complete(
+	words []string, i int64,
+	completeArg func(arg, word string) []cchat.CompletionEntry) []cchat.CompletionEntry {
+
+	if i == 0 {
+		found := cmds.Find(words[0])
+
+		var entries = make([]cchat.CompletionEntry, 0, len(found)+1)
+		if strings.HasPrefix("help", strings.ToLower(words[0])) {
+			entries = append(entries, cchat.CompletionEntry{
+				Raw:       "help",
+				Text:      text.Plain("help"),
+				Secondary: text.Plain("Prints the help message"),
+			})
+		}
 
-			return bprintf("Message %d sent at %v.", m.ID, m.Timestamp.Time()), nil
-		},
-	},
-	{
-		Name: "info",
-		Desc: "Print information as JSON",
-		RunFunc: func(ch shared.Channel, argv []string) ([]byte, error) {
-			channel, err := ch.State.Channel(ch.ID)
-			if err != nil {
-				return nil, errors.Wrap(err, "failed to get channel")
-			}
+		for _, cmd := range found {
+			entries = append(entries, cchat.CompletionEntry{
+				Raw:       cmd.Name,
+				Text:      text.Plain(cmd.Name),
+				Secondary: text.Plain(cmd.Desc),
+			})
+		}
 
-			b, err := json.MarshalIndent(channel, "", "  ")
-			if err != nil {
-				return nil, errors.Wrap(err, "failed to marshal to JSON")
-			}
+		return entries
+	}
 
-			return b, nil
-		},
-	},
-	{
-		Name: "list-channels",
-		Desc: "Print all channels of this guild and their topics",
-		RunFunc: func(ch shared.Channel, argv []string) ([]byte, error) {
-			channels, err := ch.State.Channels(ch.GuildID)
-			if err != nil {
-				return nil, errors.Wrap(err, "failed to get channels")
-			}
+	cmd := cmds.FindExact(words[0])
+	if cmd == nil {
+		return nil
+	}
 
-			var buf bytes.Buffer
-			for _, ch := range channels {
-				fmt.Fprintf(&buf, "#%s (NSFW %t): %s\n", ch.Name, ch.NSFW, ch.Topic)
-			}
+	name, _ := cmd.Args.At(int(i) - 1)
+	if name == "" {
+		return nil
+	}
 
-			return buf.Bytes(), nil
-		},
-	},
-	{
-		Name: "presence",
-		Args: Arguments{"mention:user"},
-		Desc: "Print JSON of a member/user's presence state",
-		RunFunc: func(ch shared.Channel, argv []string) ([]byte, error) {
-			if err := assertArgc(argv, 1); err != nil {
-				return nil, err
+	if options := cmd.Args.Options(int(i) - 1); options != nil {
+		var entries []cchat.CompletionEntry
+		for _, option := range options {
+			if strings.HasPrefix(option, words[i]) {
+				entries = append(entries, cchat.CompletionEntry{
+					Raw:  option,
+					Text: text.Plain(option),
+				})
 			}
+		}
+		return entries
+	}
 
-			var user arguments.UserMention
-			if err := user.Parse(argv[0]); err != nil {
-				return nil, err
-			}
+	if completeArg != nil {
+		return completeArg(name, words[i])
+	}
 
-			p, err := ch.State.Presence(ch.GuildID, user.ID())
-			if err != nil {
-				return nil, err
-			}
+	return nil
+}
 
-			return renderJSON(p)
+// World returns the commands that run in the given channel.
+func World(ch shared.Channel) Commands {
+	return Commands{
+		{
+			Name: "send-embed",
+			Args: Arguments{"-t title", "-c color", "description"},
+			Desc: "Send a basic embed to the current channel",
+			RunFunc: func(argv []string) ([]byte, error) {
+				var embed discord.Embed
+				var color uint // no Uint32Var
+
+				fs := flag.NewFlagSet("send-embed", 0)
+				fs.SetOutput(ioutil.Discard)
+				fs.StringVar(&embed.Title, "t", "", "Embed title")
+				fs.UintVar(&color, "c", 0xFFFFFF, "Embed color")
+
+				if err := fs.Parse(argv); err != nil {
+					return nil, err
+				}
+
+				embed.Description = fs.Arg(0)
+				embed.Color = discord.Color(color)
+
+				m, err := ch.State.SendEmbed(ch.ID, embed)
+				if err != nil {
+					return nil, errors.Wrap(err, "failed to send embed")
+				}
+
+				return bprintf("Message %d sent at %v.", m.ID, m.Timestamp.Time()), nil
+			},
 		},
-	},
-	{
-		Name: "member",
-		Args: Arguments{"mention:user"},
-		Desc: "Print JSON of a member/user's member state",
-		RunFunc: func(ch shared.Channel, argv []string) ([]byte, error) {
-			if err := assertArgc(argv, 1); err != nil {
-				return nil, err
-			}
-
-			if !ch.GuildID.IsValid() {
-				return nil, errors.New("channel not in guild")
-			}
-
-			var user arguments.UserMention
-			if err := user.Parse(argv[0]); err != nil {
-				return nil, err
-			}
-
-			m, err := ch.State.Cabinet.Member(ch.GuildID, user.ID())
-			if err != nil {
-				return nil, err
-			}
-
-			return renderJSON(m)
+		{
+			Name: "react",
+			Args: Arguments{"-m message", "mention:emoji"},
+			Desc: "React to the latest or the given message with an emoji",
+			RunFunc: func(argv []string) ([]byte, error) {
+				var msgID uint64
+
+				fs := flag.NewFlagSet("react", 0)
+				fs.SetOutput(ioutil.Discard)
+				fs.Uint64Var(&msgID, "m", 0, "Message ID")
+
+				if err := fs.Parse(argv); err != nil {
+					return nil, err
+				}
+
+				if err := AssertArgc(fs.Args(), 1); err != nil {
+					return nil, err
+				}
+
+				var emoji arguments.Emoji
+				if err := emoji.Parse(fs.Arg(0)); err != nil {
+					return nil, err
+				}
+
+				id := discord.MessageID(msgID)
+				if !id.IsValid() {
+					messages, err := ch.Messages()
+					if err != nil || len(messages) == 0 {
+						return nil, errors.New("no message to react to")
+					}
+
+					for _, m := range messages {
+						if m.ID > id {
+							id = m.ID
+						}
+					}
+				}
+
+				err := ch.State.React(ch.ID, id, discord.APIEmoji(emoji.APIString()))
+				if err != nil {
+					return nil, errors.Wrap(err, "failed to react")
+				}
+
+				return bprintf("Reacted to message %d with %s.", id, emoji.String()), nil
+			},
+		},
+		{
+			Name: "pins",
+			Desc: "Print all pinned messages in this channel",
+			RunFunc: func(argv []string) ([]byte, error) {
+				if err := AssertArgc(argv, 0); err != nil {
+					return nil, err
+				}
+
+				pins, err := ch.State.PinnedMessages(ch.ID)
+				if err != nil {
+					return nil, errors.Wrap(err, "failed to get pinned messages")
+				}
+
+				if len(pins) == 0 {
+					return []byte("No pinned messages."), nil
+				}
+
+				var buf bytes.Buffer
+				for _, m := range pins {
+					// Discord doesn't send the guild ID in this endpoint.
+					m.GuildID = ch.GuildID
+
+					fmt.Fprintf(&buf,
+						"%s (%d) at %s:\n",
+						m.Author.Username, m.ID, m.Timestamp.Format(time.RFC1123),
+					)
+
+					content := segments.ParseMessage(&m, ch.State.Cabinet)
+					for _, line := range strings.Split(content.Content, "\n") {
+						buf.WriteByte('\t')
+						buf.WriteString(line)
+						buf.WriteByte('\n')
+					}
+
+					buf.WriteByte('\n')
+				}
+
+				return buf.Bytes(), nil
+			},
+		},
+		{
+			Name: "search",
+			Args: Arguments{"query"},
+			Desc: "Search for messages; filters: from: mentions: in: has: before: after:",
+			RunFunc: func(argv []string) ([]byte, error) {
+				q, err := search.ParseQuery(argv)
+				if err != nil {
+					return nil, err
+				}
+
+				r, err := search.Search(ch.State, ch.GuildID, ch.ID, q)
+				if err != nil {
+					return nil, err
+				}
+
+				hits := r.Hits()
+				if len(hits) == 0 {
+					return []byte("No messages found."), nil
+				}
+
+				var buf bytes.Buffer
+				fmt.Fprintf(&buf, "Showing %d of %d results.\n", len(hits), r.TotalResults)
+
+				// Command output can't link to messages, so the results are shown
+				// with links in the guild's search server too.
+				if ch.GuildID.IsValid() {
+					ch.State.Call(&search.ResultsEvent{GuildID: ch.GuildID, Hits: hits})
+					buf.WriteString("Open 🔍 Search to jump to them.\n")
+				}
+
+				buf.WriteByte('\n')
+
+				for _, m := range hits {
+					var chName = m.ChannelID.String()
+					if c, err := ch.State.Cabinet.Channel(m.ChannelID); err == nil {
+						chName = shared.ChannelName(*c)
+					}
+
+					fmt.Fprintf(&buf,
+						"%s (%d) in %s at %s:\n",
+						m.Author.Username, m.ID, chName, m.Timestamp.Format(time.RFC1123),
+					)
+
+					content := segments.ParseMessage(&m, ch.State.Cabinet)
+					for _, line := range strings.Split(content.Content, "\n") {
+						buf.WriteByte('\t')
+						buf.WriteString(line)
+						buf.WriteByte('\n')
+					}
+
+					buf.WriteByte('\n')
+				}
+
+				return buf.Bytes(), nil
+			},
+		},
+		{
+			Name: "mute",
+			Args: Arguments{"duration"},
+			Desc: "Mute this channel, optionally for the duration only",
+			RunFunc: func(argv []string) ([]byte, error) {
+				d, err := state.ParseMuteDuration(argv)
+				if err != nil {
+					return nil, err
+				}
+
+				if err := ch.State.MuteChannel(ch.ID, d); err != nil {
+					return nil, err
+				}
+
+				return []byte("Channel muted."), nil
+			},
+		},
+		{
+			Name: "unmute",
+			Desc: "Unmute this channel",
+			RunFunc: func(argv []string) ([]byte, error) {
+				if err := AssertArgc(argv, 0); err != nil {
+					return nil, err
+				}
+
+				if err := ch.State.UnmuteChannel(ch.ID); err != nil {
+					return nil, err
+				}
+
+				return []byte("Channel unmuted."), nil
+			},
+		},
+		{
+			Name: "mute-category",
+			Args: Arguments{"duration"},
+			Desc: "Mute this channel's category, optionally for the duration only",
+			RunFunc: func(argv []string) ([]byte, error) {
+				d, err := state.ParseMuteDuration(argv)
+				if err != nil {
+					return nil, err
+				}
+
+				catID, err := categoryID(ch)
+				if err != nil {
+					return nil, err
+				}
+
+				if err := ch.State.MuteChannel(catID, d); err != nil {
+					return nil, err
+				}
+
+				return []byte("Category muted."), nil
+			},
+		},
+		{
+			Name: "unmute-category",
+			Desc: "Unmute this channel's category",
+			RunFunc: func(argv []string) ([]byte, error) {
+				if err := AssertArgc(argv, 0); err != nil {
+					return nil, err
+				}
+
+				catID, err := categoryID(ch)
+				if err != nil {
+					return nil, err
+				}
+
+				if err := ch.State.UnmuteChannel(catID); err != nil {
+					return nil, err
+				}
+
+				return []byte("Category unmuted."), nil
+			},
 		},
-	},
+		{
+			Name: "notifications",
+			Args: Arguments{"all|mentions|nothing|default"},
+			Desc: "Set the message notification level of this channel",
+			RunFunc: func(argv []string) ([]byte, error) {
+				if err := AssertArgc(argv, 1); err != nil {
+					return nil, err
+				}
+
+				n, err := state.ParseNotifications(argv[0])
+				if err != nil {
+					return nil, err
+				}
+
+				if err := ch.State.SetChannelNotifications(ch.ID, n); err != nil {
+					return nil, err
+				}
+
+				return bprintf("Notifications set to %s.", argv[0]), nil
+			},
+		},
+		{
+			Name: "info",
+			Desc: "Print information as JSON",
+			RunFunc: func(argv []string) ([]byte, error) {
+				channel, err := ch.State.Channel(ch.ID)
+				if err != nil {
+					return nil, errors.Wrap(err, "failed to get channel")
+				}
+
+				b, err := json.MarshalIndent(channel, "", "  ")
+				if err != nil {
+					return nil, errors.Wrap(err, "failed to marshal to JSON")
+				}
+
+				return b, nil
+			},
+		},
+		{
+			Name: "list-channels",
+			Desc: "Print all channels of this guild and their topics",
+			RunFunc: func(argv []string) ([]byte, error) {
+				channels, err := ch.State.Channels(ch.GuildID)
+				if err != nil {
+					return nil, errors.Wrap(err, "failed to get channels")
+				}
+
+				var buf bytes.Buffer
+				for _, ch := range channels {
+					fmt.Fprintf(&buf, "#%s (NSFW %t): %s\n", ch.Name, ch.NSFW, ch.Topic)
+				}
+
+				return buf.Bytes(), nil
+			},
+		},
+		{
+			Name: "presence",
+			Args: Arguments{"mention:user"},
+			Desc: "Print JSON of a member/user's presence state",
+			RunFunc: func(argv []string) ([]byte, error) {
+				if err := AssertArgc(argv, 1); err != nil {
+					return nil, err
+				}
+
+				var user arguments.UserMention
+				if err := user.Parse(argv[0]); err != nil {
+					return nil, err
+				}
+
+				p, err := ch.State.Presence(ch.GuildID, user.ID())
+				if err != nil {
+					return nil, err
+				}
+
+				return renderJSON(p)
+			},
+		},
+		{
+			Name: "member",
+			Args: Arguments{"mention:user"},
+			Desc: "Print JSON of a member/user's member state",
+			RunFunc: func(argv []string) ([]byte, error) {
+				if err := AssertArgc(argv, 1); err != nil {
+					return nil, err
+				}
+
+				if !ch.GuildID.IsValid() {
+					return nil, errors.New("channel not in guild")
+				}
+
+				var user arguments.UserMention
+				if err := user.Parse(argv[0]); err != nil {
+					return nil, err
+				}
+
+				m, err := ch.State.Cabinet.Member(ch.GuildID, user.ID())
+				if err != nil {
+					return nil, err
+				}
+
+				return renderJSON(m)
+			},
+		},
+	}
 }
 
-func assertArgc(argv []string, argc int) error {
+// AssertArgc errors out if argv doesn't have exactly argc arguments.
+func AssertArgc(argv []string, argc int) error {
 	switch {
 	case len(argv) > argc:
 		return errors.New("too many arguments")
@@ -199,6 +505,20 @@ func assertArgc(argv []string, argc int) error {
 	}
 }
 
+// categoryID returns the ID of the channel's category.
+func categoryID(ch shared.Channel) (discord.ChannelID, error) {
+	c, err := ch.State.Channel(ch.ID)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get channel")
+	}
+
+	if !c.CategoryID.IsValid() {
+		return 0, errors.New("channel not in a category")
+	}
+
+	return c.CategoryID, nil
+}
+
 func renderJSON(v interface{}) ([]byte, error) {
 	b, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
@@ -1,9 +1,14 @@
 package action
 
 import (
+	"strings"
+
+	"github.com/diamondburned/arikawa/v2/api"
 	"github.com/diamondburned/arikawa/v2/discord"
 	"github.com/diamondburned/cchat"
 	"github.com/diamondburned/cchat-discord/internal/discord/channel/shared"
+	"github.com/diamondburned/cchat-discord/internal/discord/state/outbox"
+	"github.com/diamondburned/cchat-discord/internal/segments/reaction"
 	"github.com/pkg/errors"
 )
 
@@ -18,26 +23,118 @@ func New(ch shared.Channel) Actioner {
 }
 
 const (
-	ActionDelete = "Delete"
+	ActionDelete  = "Delete"
+	ActionPin     = "Pin"
+	ActionUnpin   = "Unpin"
+	ActionPublish = "Publish"
+
+	// ActionShowContext loads the messages around the message that is replied
+	// to.
+	ActionShowContext = "Show Context"
+
+	// ActionMarkUnread marks the channel as unread from the message onwards.
+	ActionMarkUnread = "Mark Unread From Here"
+
+	// ActionRetrySend and ActionCancelSend are only available for messages
+	// queued in the outbox.
+	ActionRetrySend  = "Retry Sending"
+	ActionCancelSend = "Cancel Sending"
+
+	// ActionAddReaction and ActionRemoveReaction are prefixes; the full action
+	// string has the reaction label appended after a space.
+	ActionAddReaction    = "Add Reaction"
+	ActionRemoveReaction = "Remove My Reaction"
 )
 
 var ErrUnknownAction = errors.New("unknown message action")
 
 func (ac Actioner) Do(action, id string) error {
+	if nonce, ok := outbox.ParseID(id); ok {
+		return ac.doPending(action, nonce)
+	}
+
 	s, err := discord.ParseSnowflake(id)
 	if err != nil {
 		return errors.Wrap(err, "Failed to parse ID")
 	}
 
-	switch action {
-	case ActionDelete:
+	switch {
+	case action == ActionDelete:
 		return ac.State.DeleteMessage(ac.ID, discord.MessageID(s))
+
+	case action == ActionPin:
+		return ac.State.PinMessage(ac.ID, discord.MessageID(s))
+
+	case action == ActionUnpin:
+		return ac.State.UnpinMessage(ac.ID, discord.MessageID(s))
+
+	case action == ActionPublish:
+		return ac.crosspost(discord.MessageID(s))
+
+	case action == ActionShowContext:
+		return ac.showContext(discord.MessageID(s))
+
+	case action == ActionMarkUnread:
+		return ac.State.Unread.MarkUnread(ac.ID, discord.MessageID(s))
+
+	case strings.HasPrefix(action, ActionAddReaction+" "):
+		label := strings.TrimPrefix(action, ActionAddReaction+" ")
+
+		e, err := ac.findReaction(discord.MessageID(s), label)
+		if err != nil {
+			return err
+		}
+
+		return ac.State.React(ac.ID, discord.MessageID(s), e.APIString())
+
+	case strings.HasPrefix(action, ActionRemoveReaction+" "):
+		label := strings.TrimPrefix(action, ActionRemoveReaction+" ")
+
+		e, err := ac.findReaction(discord.MessageID(s), label)
+		if err != nil {
+			return err
+		}
+
+		return ac.State.Unreact(ac.ID, discord.MessageID(s), e.APIString())
+
+	default:
+		return ErrUnknownAction
+	}
+}
+
+// doPending does the action on a message queued in the outbox.
+func (ac Actioner) doPending(action, nonce string) error {
+	switch action {
+	case ActionRetrySend:
+		return ac.State.Outbox.Retry(nonce)
+	case ActionCancelSend:
+		return ac.State.Outbox.Cancel(nonce)
 	default:
 		return ErrUnknownAction
 	}
 }
 
+// findReaction finds the reaction emoji with the given label in the message.
+func (ac Actioner) findReaction(id discord.MessageID, label string) (*discord.Emoji, error) {
+	m, err := ac.State.Cabinet.Message(ac.ID, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get message")
+	}
+
+	for _, r := range m.Reactions {
+		if reaction.Label(r.Emoji) == label {
+			return &r.Emoji, nil
+		}
+	}
+
+	return nil, errors.New("unknown reaction " + label)
+}
+
 func (ac Actioner) Actions(id string) []string {
+	if nonce, ok := outbox.ParseID(id); ok {
+		return pendingActions(ac.State.Outbox, nonce)
+	}
+
 	s, err := discord.ParseSnowflake(id)
 	if err != nil {
 		return nil
@@ -54,20 +151,148 @@ func (ac Actioner) Actions(id string) []string {
 		return nil
 	}
 
-	// Can we have delete? We can if this is our own message.
-	var canDelete = m.Author.ID == u.ID
+	var canManage = ac.canManageMessages(u.ID)
 
-	// We also can if we have the Manage Messages permission, which would allow
-	// us to delete others' messages.
-	if !canDelete {
-		canDelete = ac.canManageMessages(u.ID)
-	}
+	// Can we have delete? We can if this is our own message. We also can if we
+	// have the Manage Messages permission, which would allow us to delete
+	// others' messages.
+	var canDelete = m.Author.ID == u.ID || canManage
+
+	// Anyone can pin messages in a direct message channel. Guild channels
+	// require the Manage Messages permission.
+	var canPin = !ac.GuildID.IsValid() || canManage
+
+	var actions = make([]string, 0, len(m.Reactions)+3)
 
 	if canDelete {
-		return []string{ActionDelete}
+		actions = append(actions, ActionDelete)
 	}
 
-	return []string{}
+	if ac.canPublish(*m, u.ID, canManage) {
+		actions = append(actions, ActionPublish)
+	}
+
+	if m.Reference != nil && m.Reference.MessageID.IsValid() {
+		actions = append(actions, ActionShowContext)
+	}
+
+	actions = append(actions, ActionMarkUnread)
+
+	if canPin {
+		if m.Pinned {
+			actions = append(actions, ActionUnpin)
+		} else {
+			actions = append(actions, ActionPin)
+		}
+	}
+
+	// Reacting with an existing emoji only requires the Read Message History
+	// permission.
+	if ac.HasPermission(discord.PermissionReadMessageHistory) {
+		for _, r := range m.Reactions {
+			if r.Me {
+				actions = append(actions, ActionRemoveReaction+" "+reaction.Label(r.Emoji))
+			} else {
+				actions = append(actions, ActionAddReaction+" "+reaction.Label(r.Emoji))
+			}
+		}
+	}
+
+	return actions
+}
+
+// pendingActions returns the actions for a message queued in the outbox.
+// Messages that are being sent can't be touched.
+func pendingActions(o *outbox.Outbox, nonce string) []string {
+	e, ok := o.Entry(nonce)
+	if !ok || e.Status == outbox.Sending {
+		return nil
+	}
+
+	return []string{ActionRetrySend, ActionCancelSend}
+}
+
+// canPublish returns whether or not the message can be published to the
+// channels following this one. Users can publish their own messages if they can
+// send messages, but they need the Manage Messages permission to publish
+// others'.
+func (ac Actioner) canPublish(m discord.Message, userID discord.UserID, canManage bool) bool {
+	// Only messages in announcement channels can be published, and only once.
+	c, err := ac.Self()
+	if err != nil || c.Type != discord.GuildNews {
+		return false
+	}
+
+	if m.Flags&discord.CrosspostedMessage != 0 {
+		return false
+	}
+
+	switch m.Type {
+	case discord.DefaultMessage, discord.InlinedReplyMessage:
+	default:
+		return false
+	}
+
+	if m.Author.ID == userID {
+		return ac.HasPermission(discord.PermissionSendMessages)
+	}
+
+	return canManage
+}
+
+// crosspost publishes the message with the given ID. Arikawa does not have a
+// method for this yet.
+func (ac Actioner) crosspost(id discord.MessageID) error {
+	return ac.State.FastRequest(
+		"POST",
+		api.EndpointChannels+ac.ID.String()+"/messages/"+id.String()+"/crosspost",
+	)
+}
+
+// ContextLimit is the number of messages loaded around a referenced message.
+const ContextLimit = 25
+
+// ContextEvent is dispatched into the state when the context of a message is
+// loaded. Messengers of the channel should add the messages into their
+// containers.
+type ContextEvent struct {
+	ChannelID discord.ChannelID
+	Messages  []discord.Message
+}
+
+// showContext fetches the messages around the message that the given message
+// replies to.
+func (ac Actioner) showContext(id discord.MessageID) error {
+	m, err := ac.State.Message(ac.ID, id)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get message")
+	}
+
+	if m.Reference == nil || !m.Reference.MessageID.IsValid() {
+		return errors.New("message is not a reply")
+	}
+
+	// Replies from other channels can't be shown in this one.
+	if m.Reference.ChannelID.IsValid() && m.Reference.ChannelID != ac.ID {
+		return errors.New("replied message is in another channel")
+	}
+
+	msgs, err := ac.State.MessagesAround(ac.ID, m.Reference.MessageID, ContextLimit)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get messages")
+	}
+
+	// Discord sucks.
+	for i := range msgs {
+		msgs[i].GuildID = ac.GuildID
+	}
+
+	ac.State.Call(&ContextEvent{
+		ChannelID: ac.ID,
+		Messages:  msgs,
+	})
+
+	return nil
 }
 
 // canManageMessages returns whether or not the user is allowed to manage
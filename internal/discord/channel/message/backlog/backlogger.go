@@ -2,6 +2,7 @@ package backlog
 
 import (
 	"context"
+	"log"
 
 	"github.com/diamondburned/arikawa/v2/discord"
 	"github.com/diamondburned/cchat"
@@ -24,9 +25,7 @@ func (bl Backlogger) Backlog(ctx context.Context, b cchat.ID, c cchat.MessagesCo
 		return errors.Wrap(err, "Failed to parse snowflake")
 	}
 
-	s := bl.State.WithContext(ctx)
-
-	m, err := s.MessagesBefore(bl.ID, discord.MessageID(p), uint(bl.State.MaxMessages()))
+	m, err := bl.messagesBefore(ctx, discord.MessageID(p))
 	if err != nil {
 		return errors.Wrap(err, "Failed to get messages")
 	}
@@ -36,7 +35,41 @@ func (bl Backlogger) Backlog(ctx context.Context, b cchat.ID, c cchat.MessagesCo
 		m.GuildID = bl.GuildID
 
 		c.CreateMessage(message.NewBacklogMessage(m, bl.State))
+		message.ResolveReference(ctx, c, m, bl.State)
 	}
 
 	return nil
 }
+
+// messagesBefore returns a page of messages before the given ID. The page is
+// served from the message cache if the cache has a full page of contiguous
+// messages, otherwise it is fetched and added into the cache.
+func (bl Backlogger) messagesBefore(ctx context.Context, before discord.MessageID) ([]discord.Message, error) {
+	var limit = bl.State.MaxMessages()
+	var cache = bl.State.MessageCache
+
+	if cache != nil {
+		if m := cache.Before(bl.ID, before, limit); len(m) == limit {
+			return m, nil
+		}
+	}
+
+	s := bl.State.WithContext(ctx)
+
+	m, err := s.MessagesBefore(bl.ID, before, uint(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	if cache != nil {
+		for i := range m {
+			m[i].GuildID = bl.GuildID
+		}
+
+		if err := cache.Backfill(bl.ID, before, m); err != nil {
+			log.Println("[Discord] Failed to backfill message cache:", err)
+		}
+	}
+
+	return m, nil
+}
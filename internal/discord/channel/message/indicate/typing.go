@@ -19,7 +19,7 @@ func NewTyping(ch shared.Channel) cchat.TypingIndicator {
 }
 
 func (ti TypingIndicator) Typing() error {
-	if !config.BroadcastTyping() {
+	if !config.BroadcastTyping(ti.State.Overrides, ti.GuildID, ti.ID) {
 		return nil
 	}
 
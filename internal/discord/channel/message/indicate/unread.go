@@ -3,6 +3,8 @@ package indicate
 import (
 	"github.com/diamondburned/cchat"
 	"github.com/diamondburned/cchat-discord/internal/discord/channel/shared"
+	"github.com/diamondburned/cchat-discord/internal/discord/state/unread"
+	"github.com/diamondburned/cchat-discord/internal/funcutil"
 	"github.com/diamondburned/ningen/v2/states/read"
 	"github.com/pkg/errors"
 )
@@ -24,20 +26,34 @@ func (ui UnreadIndicator) Muted() bool {
 }
 
 func (ui UnreadIndicator) UnreadIndicate(indicator cchat.UnreadContainer) (func(), error) {
-	if rs := ui.State.ReadState.FindLast(ui.ID); rs != nil {
+	if last, ok := ui.State.Unread.LastRead(ui.ID); ok {
 		c, err := ui.Self()
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to get self channel")
 		}
 
-		if c.LastMessageID > rs.LastMessageID && !ui.Muted() {
-			indicator.SetUnread(true, rs.MentionCount > 0)
+		if c.LastMessageID > last && !ui.Muted() {
+			indicator.SetUnread(true, ui.mentioned())
 		}
 	}
 
-	return ui.State.ReadState.OnUpdate(func(ev *read.UpdateEvent) {
-		if ui.ID == ev.ChannelID && !ui.Muted() {
-			indicator.SetUnread(ev.Unread, ev.MentionCount > 0)
-		}
-	}), nil
+	return funcutil.JoinCancels(
+		ui.State.ReadState.OnUpdate(func(ev *read.UpdateEvent) {
+			if ui.ID == ev.ChannelID && !ui.Muted() {
+				// Channels marked unread stay unread until they're read again.
+				unread := ev.Unread || ui.State.Unread.Marked(ui.ID)
+				indicator.SetUnread(unread, ev.MentionCount > 0)
+			}
+		}),
+		ui.State.Unread.AddHandler(func(ev *unread.UpdateEvent) {
+			if ui.ID == ev.ChannelID && !ui.Muted() {
+				indicator.SetUnread(ev.Unread, ev.Unread && ui.mentioned())
+			}
+		}),
+	), nil
+}
+
+func (ui UnreadIndicator) mentioned() bool {
+	rs := ui.State.ReadState.FindLast(ui.ID)
+	return rs != nil && rs.MentionCount > 0
 }
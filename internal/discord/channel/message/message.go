@@ -2,7 +2,9 @@ package message
 
 import (
 	"context"
+	"log"
 	"sort"
+	"sync"
 
 	"github.com/diamondburned/arikawa/v2/discord"
 	"github.com/diamondburned/arikawa/v2/gateway"
@@ -16,6 +18,7 @@ import (
 	"github.com/diamondburned/cchat-discord/internal/discord/channel/message/send"
 	"github.com/diamondburned/cchat-discord/internal/discord/channel/shared"
 	"github.com/diamondburned/cchat-discord/internal/discord/message"
+	"github.com/diamondburned/cchat-discord/internal/discord/state/outbox"
 	"github.com/diamondburned/cchat-discord/internal/funcutil"
 	"github.com/diamondburned/cchat/utils/empty"
 )
@@ -23,23 +26,60 @@ import (
 type Messenger struct {
 	empty.Messenger
 	shared.Channel
+
+	mut sync.Mutex
+	// shown has the joined containers.
+	shown map[cchat.MessagesContainer]*shown
 }
 
 var _ cchat.Messenger = (*Messenger)(nil)
 
 func New(ch shared.Channel) *Messenger {
-	return &Messenger{Channel: ch}
+	return &Messenger{
+		Channel: ch,
+		shown:   map[cchat.MessagesContainer]*shown{},
+	}
 }
 
 func (msgr *Messenger) JoinServer(ctx context.Context, ct cchat.MessagesContainer) (func(), error) {
 	state := msgr.State.WithContext(ctx)
 
-	m, err := state.Messages(msgr.ID)
-	if err != nil {
-		return nil, err
+	var addcancel = funcutil.NewCancels()
+
+	// Keep track of the messages created in the container.
+	shown := newShown(ct)
+	ct = shown
+
+	msgr.mut.Lock()
+	msgr.shown[shown.MessagesContainer] = shown
+	msgr.mut.Unlock()
+
+	addcancel(func() {
+		msgr.mut.Lock()
+		delete(msgr.shown, shown.MessagesContainer)
+		msgr.mut.Unlock()
+	})
+
+	// Keep the channel's messages on disk while it's joined.
+	if msgr.State.MessageCache != nil {
+		addcancel(msgr.State.MessageCache.Join(msgr.ID))
 	}
 
-	var addcancel = funcutil.NewCancels()
+	// Cached messages that might be outdated are rendered right away and
+	// synchronized afterwards.
+	var stale = msgr.State.MessageCache != nil && msgr.State.MessageCache.Stale(msgr.ID)
+
+	var m []discord.Message
+
+	if !stale {
+		var err error
+
+		m, err = state.Messages(msgr.ID)
+		if err != nil {
+			funcutil.JoinCancels(addcancel()...)()
+			return nil, err
+		}
+	}
 
 	if msgr.GuildID.IsValid() {
 		// Subscribe to typing events.
@@ -63,6 +103,13 @@ func (msgr *Messenger) JoinServer(ctx context.Context, ct cchat.MessagesContaine
 		}))
 	}
 
+	if stale {
+		if err := msgr.syncCached(ctx, ct); err != nil {
+			funcutil.JoinCancels(addcancel()...)()
+			return nil, err
+		}
+	}
+
 	// Only do all this if we even have any messages.
 	if len(m) > 0 {
 		// Sort messages chronologically using the ID so that the oldest messages
@@ -72,18 +119,45 @@ func (msgr *Messenger) JoinServer(ctx context.Context, ct cchat.MessagesContaine
 		// Iterate from the earliest messages to the latest messages.
 		for _, m := range m {
 			ct.CreateMessage(message.NewBacklogMessage(m, msgr.State))
+			message.ResolveReference(ctx, ct, m, msgr.State)
 		}
 
 		// Mark this channel as read.
-		msgr.State.ReadState.MarkRead(msgr.ID, m[len(m)-1].ID)
+		msgr.State.Unread.AutoMarkRead(msgr.ID, m[len(m)-1].ID)
+	}
+
+	// Show messages that are still waiting to be sent.
+	for _, e := range msgr.State.Outbox.Entries(msgr.ID) {
+		ct.CreateMessage(message.NewPending(e, msgr.GuildID, msgr.State))
 	}
 
 	// Bind the handler.
 	addcancel(
+		msgr.State.Outbox.AddHandler(func(u *outbox.Update) {
+			if u.ChannelID != msgr.ID {
+				return
+			}
+
+			switch {
+			case u.Removed:
+				ct.DeleteMessage(message.NewPendingDelete(u.Entry))
+			case u.Attempts == 1 && u.Status == outbox.Pending:
+				// The first failed attempt queues the message.
+				ct.CreateMessage(message.NewPending(u.Entry, msgr.GuildID, msgr.State))
+			default:
+				ct.UpdateMessage(message.NewPending(u.Entry, msgr.GuildID, msgr.State))
+			}
+		}),
 		msgr.State.AddHandler(func(m *gateway.MessageCreateEvent) {
 			if m.ChannelID == msgr.ID {
 				ct.CreateMessage(message.NewGuildMessageCreate(m, msgr.State))
-				msgr.State.ReadState.MarkRead(msgr.ID, m.ID)
+				message.ResolveReference(ctx, ct, m.Message, msgr.State)
+				msgr.State.Unread.AutoMarkRead(msgr.ID, m.ID)
+			}
+		}),
+		msgr.State.AddHandler(func(c *action.ContextEvent) {
+			if c.ChannelID == msgr.ID {
+				msgr.addContext(ctx, shown, c.Messages)
 			}
 		}),
 		msgr.State.AddHandler(func(m *gateway.MessageUpdateEvent) {
@@ -97,11 +171,150 @@ func (msgr *Messenger) JoinServer(ctx context.Context, ct cchat.MessagesContaine
 				ct.DeleteMessage(message.NewHeaderDelete(m))
 			}
 		}),
+		msgr.State.AddHandler(func(r *gateway.MessageReactionAddEvent) {
+			if r.ChannelID == msgr.ID {
+				msgr.updateReactions(ct, r.MessageID)
+			}
+		}),
+		msgr.State.AddHandler(func(r *gateway.MessageReactionRemoveEvent) {
+			if r.ChannelID == msgr.ID {
+				msgr.updateReactions(ct, r.MessageID)
+			}
+		}),
+		msgr.State.AddHandler(func(r *gateway.MessageReactionRemoveAllEvent) {
+			if r.ChannelID == msgr.ID {
+				msgr.updateReactions(ct, r.MessageID)
+			}
+		}),
+		msgr.State.AddHandler(func(r *gateway.MessageReactionRemoveEmojiEvent) {
+			if r.ChannelID == msgr.ID {
+				msgr.updateReactions(ct, r.MessageID)
+			}
+		}),
+		message.RefreshTimestamps(ct, msgr.State, func() []discord.Message {
+			messages, _ := msgr.Messages()
+			return messages
+		}),
 	)
 
 	return funcutil.JoinCancels(addcancel()...), nil
 }
 
+// updateReactions rerenders the message with the given ID using the reactions
+// from the state. The state is expected to have already been updated by the
+// time the reaction event handlers are called.
+func (msgr *Messenger) updateReactions(ct cchat.MessagesContainer, id discord.MessageID) {
+	m, err := msgr.State.Cabinet.Message(msgr.ID, id)
+	if err != nil {
+		return
+	}
+
+	ct.UpdateMessage(message.NewContentUpdate(*m, msgr.State))
+}
+
+// tracked returns the joined container that records the messages created in
+// the given one, or the given container if it wasn't joined.
+func (msgr *Messenger) tracked(ct cchat.MessagesContainer) cchat.MessagesContainer {
+	msgr.mut.Lock()
+	defer msgr.mut.Unlock()
+
+	if shown, ok := msgr.shown[ct]; ok {
+		return shown
+	}
+	return ct
+}
+
+// addContext adds the given messages into the container. Messages that were
+// already created in the container are skipped.
+func (msgr *Messenger) addContext(ctx context.Context, ct *shown, msgs []discord.Message) {
+	sort.Slice(msgs, func(i, j int) bool { return msgs[i].ID < msgs[j].ID })
+
+	for _, m := range msgs {
+		if ct.Has(m.ID.String()) {
+			continue
+		}
+
+		ct.CreateMessage(message.NewBacklogMessage(m, msgr.State))
+		message.ResolveReference(ctx, ct, m, msgr.State)
+	}
+}
+
+// syncCached renders the stale messages from the message cache, then fetches
+// the latest messages from the API and updates the container with the
+// difference. The cached messages are kept if the API is unreachable. An error
+// is only returned if there are no cached messages to show.
+func (msgr *Messenger) syncCached(ctx context.Context, ct cchat.MessagesContainer) error {
+	cache := msgr.State.MessageCache
+
+	cached, _ := cache.Messages(msgr.ID)
+	sort.Slice(cached, func(i, j int) bool { return cached[i].ID < cached[j].ID })
+
+	for _, m := range cached {
+		ct.CreateMessage(message.NewBacklogMessage(m, msgr.State))
+		message.ResolveReference(ctx, ct, m, msgr.State)
+	}
+
+	fresh, err := msgr.State.WithContext(ctx).Session.Messages(
+		msgr.ID, uint(msgr.State.MaxMessages()),
+	)
+	if err != nil {
+		if len(cached) == 0 {
+			return err
+		}
+
+		log.Println("[Discord] Failed to refresh cached messages:", err)
+		return nil
+	}
+
+	// Messages from the API don't have the guild ID.
+	for i := range fresh {
+		fresh[i].GuildID = msgr.GuildID
+	}
+
+	if err := cache.Sync(msgr.ID, fresh); err != nil {
+		log.Println("[Discord] Failed to update message cache:", err)
+	}
+
+	if len(fresh) == 0 {
+		return nil
+	}
+
+	sort.Slice(fresh, func(i, j int) bool { return fresh[i].ID < fresh[j].ID })
+
+	var cachedIDs = make(map[discord.MessageID]struct{}, len(cached))
+	for _, m := range cached {
+		cachedIDs[m.ID] = struct{}{}
+	}
+
+	var freshIDs = make(map[discord.MessageID]struct{}, len(fresh))
+	for _, m := range fresh {
+		freshIDs[m.ID] = struct{}{}
+
+		if _, ok := cachedIDs[m.ID]; ok {
+			ct.UpdateMessage(message.NewContentUpdate(m, msgr.State))
+		} else {
+			ct.CreateMessage(message.NewBacklogMessage(m, msgr.State))
+			message.ResolveReference(ctx, ct, m, msgr.State)
+		}
+	}
+
+	// Remove cached messages that were deleted while we were away. Cached
+	// messages older than the fresh ones are left alone.
+	for _, m := range cached {
+		if _, ok := freshIDs[m.ID]; !ok && m.ID > fresh[0].ID {
+			ct.DeleteMessage(message.NewHeaderDelete(&gateway.MessageDeleteEvent{
+				ID:        m.ID,
+				ChannelID: m.ChannelID,
+				GuildID:   m.GuildID,
+			}))
+		}
+	}
+
+	msgr.State.Unread.AutoMarkRead(msgr.ID, fresh[len(fresh)-1].ID)
+
+	return nil
+}
+
 func (msgr *Messenger) AsSender() cchat.Sender {
 	if !msgr.HasPermission(discord.PermissionSendMessages) {
 		return nil
@@ -138,7 +351,7 @@ func (msgr *Messenger) AsBacklogger() cchat.Backlogger {
 		return nil
 	}
 
-	return backlog.New(msgr.Channel)
+	return backlogger{backlog.New(msgr.Channel), msgr}
 }
 
 func (msgr *Messenger) AsTypingIndicator() cchat.TypingIndicator {
@@ -9,7 +9,6 @@ import (
 	"github.com/diamondburned/cchat-discord/internal/discord/channel/message/send/complete"
 	"github.com/diamondburned/cchat-discord/internal/discord/channel/shared"
 	"github.com/diamondburned/cchat-discord/internal/discord/config"
-	"github.com/diamondburned/cchat-discord/internal/discord/state"
 )
 
 var (
@@ -20,7 +19,9 @@ var (
 	}
 )
 
-func WrapMessage(s *state.Instance, msg cchat.SendableMessage) api.SendMessageData {
+// WrapMessage converts the message into send data for the given channel. The
+// channel's configuration overrides are taken into account.
+func WrapMessage(ch shared.Channel, msg cchat.SendableMessage) api.SendMessageData {
 	var send = api.SendMessageData{
 		Content: msg.Content(),
 	}
@@ -30,7 +31,7 @@ func WrapMessage(s *state.Instance, msg cchat.SendableMessage) api.SendMessageDa
 	}
 
 	if noncer := msg.AsNoncer(); noncer != nil {
-		send.Nonce = s.Nonces.Generate(noncer.Nonce())
+		send.Nonce = ch.State.Nonces.Generate(noncer.Nonce())
 	}
 
 	if replier := msg.AsReplier(); replier != nil {
@@ -47,7 +48,7 @@ func WrapMessage(s *state.Instance, msg cchat.SendableMessage) api.SendMessageDa
 			RepliedUser: option.False,
 		}
 
-		if config.MentionOnReply() {
+		if config.MentionOnReply(ch.State.Overrides, ch.GuildID, ch.ID) {
 			send.AllowedMentions.RepliedUser = option.True
 		}
 	}
@@ -65,8 +66,16 @@ func New(ch shared.Channel) Sender {
 	return Sender{ch}
 }
 
+// Send sends the message through the outbox. Messages that fail to send
+// because of a temporary error are queued and retried later, in which case no
+// error is returned.
 func (s Sender) Send(msg cchat.SendableMessage) error {
-	_, err := s.State.SendMessageComplex(s.ID, WrapMessage(s.State, msg))
+	var original string
+	if noncer := msg.AsNoncer(); noncer != nil {
+		original = noncer.Nonce()
+	}
+
+	_, err := s.State.Outbox.Send(s.ID, original, WrapMessage(s.Channel, msg))
 	return err
 }
 
@@ -0,0 +1,61 @@
+package message
+
+import (
+	"context"
+	"sync"
+
+	"github.com/diamondburned/cchat"
+)
+
+// shown is a container that remembers the messages created in it, so messages
+// loaded afterwards, such as the context of a reply, aren't created twice.
+type shown struct {
+	cchat.MessagesContainer
+
+	mut sync.Mutex
+	ids map[cchat.ID]struct{}
+}
+
+func newShown(ct cchat.MessagesContainer) *shown {
+	return &shown{
+		MessagesContainer: ct,
+		ids:               map[cchat.ID]struct{}{},
+	}
+}
+
+func (s *shown) CreateMessage(msg cchat.MessageCreate) {
+	s.mut.Lock()
+	s.ids[msg.ID()] = struct{}{}
+	s.mut.Unlock()
+
+	s.MessagesContainer.CreateMessage(msg)
+}
+
+func (s *shown) DeleteMessage(msg cchat.MessageDelete) {
+	s.mut.Lock()
+	delete(s.ids, msg.ID())
+	s.mut.Unlock()
+
+	s.MessagesContainer.DeleteMessage(msg)
+}
+
+// Has returns true if the message with the given ID was created in the
+// container and not deleted since.
+func (s *shown) Has(id cchat.ID) bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	_, ok := s.ids[id]
+	return ok
+}
+
+// backlogger records the backlog messages in the container if it was joined,
+// since the frontend usually gives the same container to both.
+type backlogger struct {
+	cchat.Backlogger
+	msgr *Messenger
+}
+
+func (bl backlogger) Backlog(ctx context.Context, before cchat.ID, ct cchat.MessagesContainer) error {
+	return bl.Backlogger.Backlog(ctx, before, bl.msgr.tracked(ct))
+}
@@ -1,119 +1,51 @@
+// Package config provides the global configuration registry.
 package config
 
 import (
-	"fmt"
-	"strconv"
-	"sync"
-
-	"github.com/diamondburned/cchat"
-	"github.com/pkg/errors"
+	"github.com/alecthomas/chroma/styles"
+	"github.com/diamondburned/arikawa/v2/discord"
 )
 
-var World = &registry{
-	configs: []config{
-		{"Mention on Reply", true},
-		{"Broadcast Typing", true},
-	},
-}
-
-// MentionOnReply returns true if message replies should mention users.
-func MentionOnReply() bool {
-	return World.get(0).(bool)
-}
-
-// BroadcastTyping returns true if typing events should be broadcasted.
-func BroadcastTyping() bool {
-	return World.get(1).(bool)
-}
-
-type config struct {
-	Name  string
-	Value interface{}
-}
-
-func (c config) Marshal(dst map[string]string) error {
-	switch v := c.Value.(type) {
-	case bool:
-		dst[c.Name] = strconv.FormatBool(v)
-	case string:
-		dst[c.Name] = v
-	default:
-		return cchat.ErrInvalidConfigAtField{
-			Key: c.Name,
-			Err: fmt.Errorf("unknown type %T", c.Value),
-		}
-	}
-
-	return nil
-}
-
-func (c *config) Unmarshal(src map[string]string) (err error) {
-	strVal, ok := src[c.Name]
-	if !ok {
-		return cchat.ErrInvalidConfigAtField{
-			Key: c.Name, Err: errors.New("missing field"),
-		}
-	}
+// World is the global configuration registry. Its values are shared by all
+// sessions. Each session can set its own values of the fields marked per
+// account, and override some of them per guild and per channel; see
+// Overrides.
+var World = NewRegistry()
 
-	var v interface{}
+var (
+	mentionOnReply  = World.Bool("Mention on Reply", true).Overridable().PerAccount()
+	broadcastTyping = World.Bool("Broadcast Typing", true).Overridable().PerAccount()
 
-	switch c.Value.(type) {
-	case bool:
-		v, err = strconv.ParseBool(strVal)
-	case string:
-		v = strVal
-	default:
-		err = fmt.Errorf("unknown type %T", c.Value)
-	}
+	messageCache = World.Bool("Cache Messages on Disk", false)
 
-	if err != nil {
-		return cchat.ErrInvalidConfigAtField{
-			Key: c.Name,
-			Err: err,
-		}
-	}
+	syntaxHighlighting = World.Bool("Syntax Highlighting", false).PerAccount()
+	syntaxStyle        = World.Enum("Syntax Highlighting Style", "monokai", styles.Names()...).PerAccount()
+)
 
-	c.Value = v
-	return nil
+// MentionOnReply returns true if message replies in the given channel should
+// mention users.
+func MentionOnReply(o *Overrides, guildID discord.GuildID, chID discord.ChannelID) bool {
+	return mentionOnReply.In(o, guildID, chID)
 }
 
-type registry struct {
-	mutex   sync.RWMutex
-	configs []config
+// BroadcastTyping returns true if typing events should be broadcasted in the
+// given channel.
+func BroadcastTyping(o *Overrides, guildID discord.GuildID, chID discord.ChannelID) bool {
+	return broadcastTyping.In(o, guildID, chID)
 }
 
-func (reg *registry) get(i int) interface{} {
-	reg.mutex.RLock()
-	defer reg.mutex.RUnlock()
-
-	return reg.configs[i].Value
+// MessageCache returns true if messages should be cached on disk. The cache
+// isn't encrypted, so it's off unless enabled. It only applies to sessions
+// created afterwards.
+func MessageCache() bool {
+	return messageCache.Get()
 }
 
-func (reg *registry) Configuration() (map[string]string, error) {
-	reg.mutex.RLock()
-	defer reg.mutex.RUnlock()
-
-	var configMap = map[string]string{}
-
-	for _, config := range reg.configs {
-		if err := config.Marshal(configMap); err != nil {
-			return nil, err
-		}
+// SyntaxHighlighting returns the name of the style to highlight code blocks
+// with in the account, or an empty string if they shouldn't be highlighted.
+func SyntaxHighlighting(o *Overrides) string {
+	if !syntaxHighlighting.For(o) {
+		return ""
 	}
-
-	return configMap, nil
-}
-
-func (reg *registry) SetConfiguration(cfgMap map[string]string) error {
-	reg.mutex.Lock()
-	defer reg.mutex.Unlock()
-
-	for i := range reg.configs {
-		// reference the config inside the slice
-		if err := reg.configs[i].Unmarshal(cfgMap); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return syntaxStyle.For(o)
 }
@@ -0,0 +1,318 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Bool is a boolean configuration field.
+type Bool struct {
+	reg  *Registry
+	name string
+}
+
+type boolField struct{ value, def bool }
+
+// Bool registers a new boolean field. An empty value resets the field to the
+// default.
+func (reg *Registry) Bool(name string, def bool) Bool {
+	reg.register(name, &boolField{def, def})
+	return Bool{reg, name}
+}
+
+// Get returns the current value.
+func (b Bool) Get() (v bool) {
+	b.reg.read(b.name, func(f field) { v = f.(*boolField).value })
+	return
+}
+
+// For returns the account's value if it has one, or the current value
+// otherwise. The overrides may be nil.
+func (b Bool) For(o *Overrides) bool {
+	if f := o.account(b.name); f != nil {
+		return f.(*boolField).value
+	}
+	return b.Get()
+}
+
+// PerAccount marks the field as settable per account. It returns the same
+// field for convenience.
+func (b Bool) PerAccount() Bool {
+	b.reg.markAccount(b.name)
+	return b
+}
+
+func (f *boolField) format() string { return strconv.FormatBool(f.value) }
+
+func (f *boolField) clone() field {
+	c := *f
+	return &c
+}
+
+func (f *boolField) parse(v string) error {
+	if v == "" {
+		f.value = f.def
+		return nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return errors.New("expected true or false")
+	}
+
+	f.value = b
+	return nil
+}
+
+// String is a free-form string configuration field.
+type String struct {
+	reg  *Registry
+	name string
+}
+
+type stringField struct{ value string }
+
+// String registers a new string field.
+func (reg *Registry) String(name, def string) String {
+	reg.register(name, &stringField{def})
+	return String{reg, name}
+}
+
+// Get returns the current value.
+func (s String) Get() (v string) {
+	s.reg.read(s.name, func(f field) { v = f.(*stringField).value })
+	return
+}
+
+// For returns the account's value if it has one, or the current value
+// otherwise. The overrides may be nil.
+func (s String) For(o *Overrides) string {
+	if f := o.account(s.name); f != nil {
+		return f.(*stringField).value
+	}
+	return s.Get()
+}
+
+// PerAccount marks the field as settable per account. It returns the same
+// field for convenience.
+func (s String) PerAccount() String {
+	s.reg.markAccount(s.name)
+	return s
+}
+
+func (f *stringField) format() string { return f.value }
+
+func (f *stringField) clone() field {
+	c := *f
+	return &c
+}
+
+func (f *stringField) parse(v string) error {
+	f.value = v
+	return nil
+}
+
+// Int is an integer configuration field with inclusive bounds.
+type Int struct {
+	reg  *Registry
+	name string
+}
+
+type intField struct{ value, def, min, max int }
+
+// Int registers a new integer field that must be within min and max. An empty
+// value resets the field to the default.
+func (reg *Registry) Int(name string, def, min, max int) Int {
+	reg.register(name, &intField{def, def, min, max})
+	return Int{reg, name}
+}
+
+// Get returns the current value.
+func (i Int) Get() (v int) {
+	i.reg.read(i.name, func(f field) { v = f.(*intField).value })
+	return
+}
+
+func (f *intField) format() string { return strconv.Itoa(f.value) }
+
+func (f *intField) clone() field {
+	c := *f
+	return &c
+}
+
+func (f *intField) parse(v string) error {
+	if v == "" {
+		f.value = f.def
+		return nil
+	}
+
+	i, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return errors.New("expected a number")
+	}
+
+	if i < f.min || i > f.max {
+		return errors.Errorf("expected a number from %d to %d", f.min, f.max)
+	}
+
+	f.value = i
+	return nil
+}
+
+// Enum is a configuration field that is one of the given options.
+type Enum struct {
+	reg  *Registry
+	name string
+}
+
+type enumField struct {
+	value   string
+	def     string
+	options []string
+}
+
+// Enum registers a new enumeration field. The default must be one of the
+// options. Values are matched case-insensitively. An empty value resets the
+// field to the default.
+func (reg *Registry) Enum(name, def string, options ...string) Enum {
+	reg.register(name, &enumField{def, def, options})
+	return Enum{reg, name}
+}
+
+// Get returns the current option.
+func (e Enum) Get() (v string) {
+	e.reg.read(e.name, func(f field) { v = f.(*enumField).value })
+	return
+}
+
+// For returns the account's option if it has one, or the current option
+// otherwise. The overrides may be nil.
+func (e Enum) For(o *Overrides) string {
+	if f := o.account(e.name); f != nil {
+		return f.(*enumField).value
+	}
+	return e.Get()
+}
+
+// PerAccount marks the field as settable per account. It returns the same
+// field for convenience.
+func (e Enum) PerAccount() Enum {
+	e.reg.markAccount(e.name)
+	return e
+}
+
+func (f *enumField) format() string { return f.value }
+
+func (f *enumField) clone() field {
+	c := *f
+	return &c
+}
+
+func (f *enumField) parse(v string) error {
+	if v == "" {
+		f.value = f.def
+		return nil
+	}
+
+	for _, opt := range f.options {
+		if strings.EqualFold(opt, strings.TrimSpace(v)) {
+			f.value = opt
+			return nil
+		}
+	}
+
+	return errors.Errorf("expected one of: %s", strings.Join(f.options, ", "))
+}
+
+// Duration is a duration configuration field with inclusive bounds.
+type Duration struct {
+	reg  *Registry
+	name string
+}
+
+type durationField struct{ value, def, min, max time.Duration }
+
+// Duration registers a new duration field that must be within min and max.
+// Values are formatted like "1m30s". An empty value resets the field to the
+// default.
+func (reg *Registry) Duration(name string, def, min, max time.Duration) Duration {
+	reg.register(name, &durationField{def, def, min, max})
+	return Duration{reg, name}
+}
+
+// Get returns the current value.
+func (d Duration) Get() (v time.Duration) {
+	d.reg.read(d.name, func(f field) { v = f.(*durationField).value })
+	return
+}
+
+func (f *durationField) format() string { return f.value.String() }
+
+func (f *durationField) clone() field {
+	c := *f
+	return &c
+}
+
+func (f *durationField) parse(v string) error {
+	if v == "" {
+		f.value = f.def
+		return nil
+	}
+
+	d, err := time.ParseDuration(strings.TrimSpace(v))
+	if err != nil {
+		return errors.New("expected a duration like 1m30s")
+	}
+
+	if d < f.min || d > f.max {
+		return errors.Errorf("expected a duration from %v to %v", f.min, f.max)
+	}
+
+	f.value = d
+	return nil
+}
+
+// List is a comma-separated list configuration field.
+type List struct {
+	reg  *Registry
+	name string
+}
+
+type listField struct{ value []string }
+
+// List registers a new list field. Items are trimmed and empty items are
+// dropped.
+func (reg *Registry) List(name string, def ...string) List {
+	reg.register(name, &listField{def})
+	return List{reg, name}
+}
+
+// Get returns a copy of the current list.
+func (l List) Get() (v []string) {
+	l.reg.read(l.name, func(f field) {
+		v = append([]string(nil), f.(*listField).value...)
+	})
+	return
+}
+
+func (f *listField) format() string { return strings.Join(f.value, ", ") }
+
+func (f *listField) clone() field {
+	return &listField{append([]string(nil), f.value...)}
+}
+
+func (f *listField) parse(v string) error {
+	var list []string
+
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	f.value = list
+	return nil
+}
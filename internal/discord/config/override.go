@@ -0,0 +1,376 @@
+package config
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/cchat"
+	"github.com/pkg/errors"
+)
+
+// Override values shown in scoped configurators. Default inherits the value
+// from the guild or from the global configuration.
+const (
+	OverrideDefault = "Default"
+	OverrideOn      = "On"
+	OverrideOff     = "Off"
+)
+
+// scope is either a guild or a channel. Only one of the IDs is valid.
+type scope struct {
+	guildID   discord.GuildID
+	channelID discord.ChannelID
+}
+
+// Overridable marks the field as overridable per guild and per channel. It
+// returns the same field for convenience.
+func (b Bool) Overridable() Bool {
+	b.reg.mutex.Lock()
+	defer b.reg.mutex.Unlock()
+
+	b.reg.overridable = append(b.reg.overridable, b.name)
+	return b
+}
+
+// In returns the value of the field in the given channel. A channel override
+// takes precedence over a guild override, which takes precedence over the
+// account's value and then the global value. The overrides may be nil.
+func (b Bool) In(o *Overrides, guildID discord.GuildID, chID discord.ChannelID) bool {
+	if o != nil {
+		if v, ok := o.lookup(b.name, guildID, chID); ok {
+			return v
+		}
+	}
+
+	return b.For(o)
+}
+
+// Overrides is a set of per-guild and per-channel overrides of the
+// overridable fields in a registry, along with the account's own values of the
+// fields settable per account. Each session has its own.
+type Overrides struct {
+	reg       *Registry
+	mutex     sync.RWMutex
+	overrides map[scope]map[string]bool
+	// accounts has the account's values. The fields are replaced instead of
+	// changed, so they can be read without holding the mutex.
+	accounts map[string]field
+}
+
+// NewOverrides creates an empty set of overrides for the registry.
+func (reg *Registry) NewOverrides() *Overrides {
+	return &Overrides{
+		reg:       reg,
+		overrides: map[scope]map[string]bool{},
+		accounts:  map[string]field{},
+	}
+}
+
+// account returns the account's value of the field, or nil if the account
+// doesn't have one. The overrides may be nil.
+func (o *Overrides) account(name string) field {
+	if o == nil {
+		return nil
+	}
+
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	return o.accounts[name]
+}
+
+func (o *Overrides) perAccount() []string {
+	o.reg.mutex.RLock()
+	defer o.reg.mutex.RUnlock()
+
+	return o.reg.perAccount
+}
+
+// setAccount parses the account's value of the field. An empty value removes
+// it, so the global value is used. The caller must hold the mutex.
+func (o *Overrides) setAccount(name, v string) error {
+	if strings.TrimSpace(v) == "" {
+		delete(o.accounts, name)
+		return nil
+	}
+
+	var f field
+	o.reg.read(name, func(global field) { f = global.clone() })
+
+	if err := f.parse(v); err != nil {
+		return err
+	}
+
+	o.accounts[name] = f
+	return nil
+}
+
+func (o *Overrides) lookup(name string, guildID discord.GuildID, chID discord.ChannelID) (bool, bool) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	if v, ok := o.overrides[scope{channelID: chID}][name]; ok && chID.IsValid() {
+		return v, true
+	}
+
+	if v, ok := o.overrides[scope{guildID: guildID}][name]; ok && guildID.IsValid() {
+		return v, true
+	}
+
+	return false, false
+}
+
+func (o *Overrides) overridable() []string {
+	o.reg.mutex.RLock()
+	defer o.reg.mutex.RUnlock()
+
+	return o.reg.overridable
+}
+
+// set sets or removes the override. The caller must hold the mutex.
+func (o *Overrides) set(s scope, name string, v *bool) {
+	overrides := o.overrides[s]
+
+	if v == nil {
+		delete(overrides, name)
+		if len(overrides) == 0 {
+			delete(o.overrides, s)
+		}
+		return
+	}
+
+	if overrides == nil {
+		overrides = map[string]bool{}
+		o.overrides[s] = overrides
+	}
+
+	overrides[name] = *v
+}
+
+// Guild returns a configurator for the overrides in the given guild.
+func (o *Overrides) Guild(id discord.GuildID) cchat.Configurator {
+	return scopedConfigurator{o, scope{guildID: id}}
+}
+
+// Channel returns a configurator for the overrides in the given channel.
+func (o *Overrides) Channel(id discord.ChannelID) cchat.Configurator {
+	return scopedConfigurator{o, scope{channelID: id}}
+}
+
+// Account returns a configurator for the account's values. Fields left empty
+// use the global configuration.
+func (o *Overrides) Account() cchat.Configurator {
+	return accountConfigurator{o}
+}
+
+// accountPrefix is the prefix of the saved keys of the account's values.
+const accountPrefix = "account/"
+
+// Save returns all overrides and account values as a flat map. Keys are
+// formatted as "guild/<id>/<name>", "channel/<id>/<name>" or
+// "account/<name>".
+func (o *Overrides) Save() map[string]string {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	var saved = map[string]string{}
+
+	for name, f := range o.accounts {
+		saved[accountPrefix+name] = f.format()
+	}
+
+	for s, overrides := range o.overrides {
+		var prefix string
+		if s.guildID.IsValid() {
+			prefix = "guild/" + s.guildID.String() + "/"
+		} else {
+			prefix = "channel/" + s.channelID.String() + "/"
+		}
+
+		for name, v := range overrides {
+			saved[prefix+name] = formatOverride(v, true)
+		}
+	}
+
+	return saved
+}
+
+// Load adds the overrides and account values saved with Save. Invalid keys and
+// values are skipped.
+func (o *Overrides) Load(saved map[string]string) {
+	var overridable = map[string]bool{}
+	for _, name := range o.overridable() {
+		overridable[name] = true
+	}
+
+	var perAccount = map[string]bool{}
+	for _, name := range o.perAccount() {
+		perAccount[name] = true
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for k, v := range saved {
+		if strings.HasPrefix(k, accountPrefix) {
+			if name := strings.TrimPrefix(k, accountPrefix); perAccount[name] {
+				o.setAccount(name, v)
+			}
+			continue
+		}
+
+		parts := strings.SplitN(k, "/", 3)
+		if len(parts) != 3 || !overridable[parts[2]] {
+			continue
+		}
+
+		id, err := discord.ParseSnowflake(parts[1])
+		if err != nil {
+			continue
+		}
+
+		var s scope
+
+		switch parts[0] {
+		case "guild":
+			s.guildID = discord.GuildID(id)
+		case "channel":
+			s.channelID = discord.ChannelID(id)
+		default:
+			continue
+		}
+
+		if b, err := parseOverride(v); err == nil && b != nil {
+			o.set(s, parts[2], b)
+		}
+	}
+}
+
+type scopedConfigurator struct {
+	o     *Overrides
+	scope scope
+}
+
+func (sc scopedConfigurator) Configuration() (map[string]string, error) {
+	var names = sc.o.overridable()
+
+	sc.o.mutex.RLock()
+	defer sc.o.mutex.RUnlock()
+
+	var configMap = make(map[string]string, len(names))
+
+	for _, name := range names {
+		v, ok := sc.o.overrides[sc.scope][name]
+		configMap[name] = formatOverride(v, ok)
+	}
+
+	return configMap, nil
+}
+
+// SetConfiguration updates the overrides in the given map the same way
+// Registry.SetConfiguration does. An empty value removes the override.
+func (sc scopedConfigurator) SetConfiguration(cfgMap map[string]string) error {
+	var names = sc.o.overridable()
+
+	sc.o.mutex.Lock()
+	defer sc.o.mutex.Unlock()
+
+	var firstErr error
+
+	for _, name := range names {
+		v, ok := cfgMap[name]
+		if !ok {
+			continue
+		}
+
+		b, err := parseOverride(v)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = cchat.ErrInvalidConfigAtField{Key: name, Err: err}
+			}
+			continue
+		}
+
+		sc.o.set(sc.scope, name, b)
+	}
+
+	return firstErr
+}
+
+type accountConfigurator struct {
+	o *Overrides
+}
+
+func (ac accountConfigurator) Configuration() (map[string]string, error) {
+	var names = ac.o.perAccount()
+
+	ac.o.mutex.RLock()
+	defer ac.o.mutex.RUnlock()
+
+	var configMap = make(map[string]string, len(names))
+
+	for _, name := range names {
+		if f, ok := ac.o.accounts[name]; ok {
+			configMap[name] = f.format()
+		} else {
+			configMap[name] = ""
+		}
+	}
+
+	return configMap, nil
+}
+
+// SetConfiguration updates the account's values in the given map the same way
+// Registry.SetConfiguration does. An empty value removes the account's value.
+func (ac accountConfigurator) SetConfiguration(cfgMap map[string]string) error {
+	var names = ac.o.perAccount()
+
+	ac.o.mutex.Lock()
+	defer ac.o.mutex.Unlock()
+
+	var firstErr error
+
+	for _, name := range names {
+		v, ok := cfgMap[name]
+		if !ok {
+			continue
+		}
+
+		if err := ac.o.setAccount(name, v); err != nil && firstErr == nil {
+			firstErr = cchat.ErrInvalidConfigAtField{Key: name, Err: err}
+		}
+	}
+
+	return firstErr
+}
+
+func formatOverride(v, ok bool) string {
+	switch {
+	case !ok:
+		return OverrideDefault
+	case v:
+		return OverrideOn
+	default:
+		return OverrideOff
+	}
+}
+
+// parseOverride parses the override value. A nil bool is returned for the
+// default.
+func parseOverride(v string) (*bool, error) {
+	var b bool
+
+	switch v = strings.TrimSpace(v); {
+	case v == "", strings.EqualFold(v, OverrideDefault):
+		return nil, nil
+	case strings.EqualFold(v, OverrideOn):
+		b = true
+	case strings.EqualFold(v, OverrideOff):
+		b = false
+	default:
+		return nil, errors.New("expected Default, On or Off")
+	}
+
+	return &b, nil
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"sync"
+
+	"github.com/diamondburned/cchat"
+)
+
+// Registry is a list of typed configuration fields. Fields are registered
+// with the typed constructors, which return a handle that reads the value by
+// its name.
+type Registry struct {
+	mutex  sync.RWMutex
+	fields []namedField
+	names  map[string]field
+
+	// overridable is the list of fields that can be overridden per guild or
+	// channel.
+	overridable []string
+	// perAccount is the list of fields that can be set per account.
+	perAccount []string
+}
+
+var _ cchat.Configurator = (*Registry)(nil)
+
+type namedField struct {
+	name string
+	field
+}
+
+// field is a configuration value of a certain type.
+type field interface {
+	// format formats the current value.
+	format() string
+	// parse validates and sets the value. The value must not be changed if
+	// an error is returned.
+	parse(string) error
+	// clone returns a copy of the field.
+	clone() field
+}
+
+// NewRegistry creates a new empty registry.
+func NewRegistry() *Registry {
+	return &Registry{names: map[string]field{}}
+}
+
+// register adds the field into the registry. It panics if the name is taken,
+// since fields are registered on initialization.
+func (reg *Registry) register(name string, f field) {
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+
+	if _, ok := reg.names[name]; ok {
+		panic("config: duplicate field " + name)
+	}
+
+	reg.names[name] = f
+	reg.fields = append(reg.fields, namedField{name, f})
+}
+
+// markAccount adds the field into the list of fields settable per account.
+func (reg *Registry) markAccount(name string) {
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+
+	reg.perAccount = append(reg.perAccount, name)
+}
+
+// read calls fn with the field of the given name while the registry is read
+// locked.
+func (reg *Registry) read(name string, fn func(field)) {
+	reg.mutex.RLock()
+	defer reg.mutex.RUnlock()
+
+	fn(reg.names[name])
+}
+
+// Configuration returns all fields formatted in their string form.
+func (reg *Registry) Configuration() (map[string]string, error) {
+	reg.mutex.RLock()
+	defer reg.mutex.RUnlock()
+
+	var configMap = make(map[string]string, len(reg.fields))
+
+	for _, f := range reg.fields {
+		configMap[f.name] = f.format()
+	}
+
+	return configMap, nil
+}
+
+// SetConfiguration updates the fields that are in the given map. Missing fields
+// keep their current values and unknown keys are ignored, so configurations
+// saved by older or newer versions still load. Valid fields are always applied;
+// the error of the first invalid field is returned as an
+// ErrInvalidConfigAtField.
+func (reg *Registry) SetConfiguration(cfgMap map[string]string) error {
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+
+	var firstErr error
+
+	for _, f := range reg.fields {
+		v, ok := cfgMap[f.name]
+		if !ok {
+			continue
+		}
+
+		if err := f.parse(v); err != nil && firstErr == nil {
+			firstErr = cchat.ErrInvalidConfigAtField{
+				Key: f.name,
+				Err: err,
+			}
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/cchat"
+)
+
+func TestRegistry(t *testing.T) {
+	reg := NewRegistry()
+
+	b := reg.Bool("Bool", true)
+	i := reg.Int("Int", 5, 1, 10)
+	e := reg.Enum("Enum", "Low", "Low", "High")
+	d := reg.Duration("Duration", time.Second, 0, time.Minute)
+	l := reg.List("List", "a")
+
+	err := reg.SetConfiguration(map[string]string{
+		"Bool":     "false",
+		"Int":      "11",
+		"Enum":     "high",
+		"Duration": "1m",
+		"List":     "x, ,y",
+		"Unknown":  "ignored",
+	})
+
+	var fieldErr cchat.ErrInvalidConfigAtField
+	if !errors.As(err, &fieldErr) || fieldErr.Key != "Int" {
+		t.Fatalf("Expected an error at Int, got %v", err)
+	}
+
+	if b.Get() {
+		t.Error("Bool was not updated")
+	}
+	if i.Get() != 5 {
+		t.Errorf("Invalid Int was applied: %d", i.Get())
+	}
+	if e.Get() != "High" {
+		t.Errorf("Unexpected Enum: %q", e.Get())
+	}
+	if d.Get() != time.Minute {
+		t.Errorf("Unexpected Duration: %v", d.Get())
+	}
+	if got := l.Get(); len(got) != 2 || got[0] != "x" || got[1] != "y" {
+		t.Errorf("Unexpected List: %q", got)
+	}
+
+	// Missing fields are left alone and empty fields are reset.
+	if err := reg.SetConfiguration(map[string]string{"Bool": ""}); err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+
+	if !b.Get() || e.Get() != "High" {
+		t.Errorf("Unexpected values after a partial update: %v, %q", b.Get(), e.Get())
+	}
+
+	cfg, _ := reg.Configuration()
+	if cfg["Duration"] != "1m0s" || cfg["List"] != "x, y" {
+		t.Errorf("Unexpected configuration: %v", cfg)
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	reg := NewRegistry()
+	b := reg.Bool("Bool", true).Overridable()
+
+	o := reg.NewOverrides()
+
+	if err := o.Guild(1).SetConfiguration(map[string]string{"Bool": "off"}); err != nil {
+		t.Fatal("Failed to set guild override:", err)
+	}
+	if err := o.Channel(2).SetConfiguration(map[string]string{"Bool": "On"}); err != nil {
+		t.Fatal("Failed to set channel override:", err)
+	}
+
+	if !b.In(o, 1, 2) {
+		t.Error("Channel override was not used")
+	}
+	if b.In(o, 1, 3) {
+		t.Error("Guild override was not used")
+	}
+	if !b.In(o, 4, 5) || !b.In(nil, 1, 3) {
+		t.Error("Global value was not used")
+	}
+
+	// Overrides of other sessions are separate.
+	if !b.In(reg.NewOverrides(), 1, 3) {
+		t.Error("Guild override leaked into another session")
+	}
+
+	if err := o.Channel(2).SetConfiguration(map[string]string{"Bool": "maybe"}); err == nil {
+		t.Error("Expected an error for an invalid override")
+	}
+
+	restored := reg.NewOverrides()
+	restored.Load(o.Save())
+
+	if !b.In(restored, 1, 2) || b.In(restored, 1, 3) {
+		t.Errorf("Overrides were not restored: %v", restored.Save())
+	}
+
+	if err := o.Channel(2).SetConfiguration(map[string]string{"Bool": ""}); err != nil {
+		t.Fatal("Failed to remove channel override:", err)
+	}
+
+	cfg, _ := o.Channel(2).Configuration()
+	if cfg["Bool"] != OverrideDefault || b.In(o, 1, 2) {
+		t.Errorf("Channel override was not removed: %v", cfg)
+	}
+}
+
+func TestAccount(t *testing.T) {
+	reg := NewRegistry()
+	b := reg.Bool("Bool", true).Overridable().PerAccount()
+	s := reg.String("String", "global").PerAccount()
+	reg.Int("Global", 1, 0, 10)
+
+	o := reg.NewOverrides()
+
+	err := o.Account().SetConfiguration(map[string]string{
+		"Bool":   "false",
+		"String": "account",
+		"Global": "5",
+	})
+	if err != nil {
+		t.Fatal("Failed to set account values:", err)
+	}
+
+	if b.For(o) || s.For(o) != "account" {
+		t.Errorf("Account values were not used: %v, %q", b.For(o), s.For(o))
+	}
+
+	// Guild and channel overrides still take precedence.
+	o.Guild(1).SetConfiguration(map[string]string{"Bool": OverrideOn})
+
+	if !b.In(o, 1, 2) || b.In(o, 3, 4) {
+		t.Error("Account value has the wrong precedence")
+	}
+
+	// Other accounts and the global values are left alone.
+	if !b.For(reg.NewOverrides()) || !b.Get() || s.Get() != "global" {
+		t.Error("Account value leaked")
+	}
+
+	cfg, _ := o.Account().Configuration()
+	if _, ok := cfg["Global"]; ok || cfg["String"] != "account" {
+		t.Errorf("Unexpected account configuration: %v", cfg)
+	}
+
+	if err := o.Account().SetConfiguration(map[string]string{"Bool": "maybe"}); err == nil {
+		t.Error("Expected an error for an invalid account value")
+	}
+
+	restored := reg.NewOverrides()
+	restored.Load(o.Save())
+
+	if b.For(restored) || s.For(restored) != "account" || !b.In(restored, 1, 0) {
+		t.Errorf("Account values were not restored: %v", restored.Save())
+	}
+
+	// Empty values fall back to the global ones.
+	if err := o.Account().SetConfiguration(map[string]string{"String": ""}); err != nil {
+		t.Fatal("Failed to remove account value:", err)
+	}
+
+	if s.For(o) != "global" {
+		t.Errorf("Account value was not removed: %q", s.For(o))
+	}
+}
@@ -0,0 +1,106 @@
+package guild
+
+import (
+	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-discord/internal/discord/channel/commands"
+	"github.com/diamondburned/cchat-discord/internal/discord/state"
+	"github.com/pkg/errors"
+)
+
+// commands returns the commands that run in the guild.
+func (g *Guild) commands() commands.Commands {
+	return commands.Commands{
+		{
+			Name: "mark-read",
+			Desc: "Mark all channels in this guild as read",
+			RunFunc: func(argv []string) ([]byte, error) {
+				if err := commands.AssertArgc(argv, 0); err != nil {
+					return nil, err
+				}
+
+				if err := g.state.MarkGuildRead(g.id); err != nil {
+					return nil, err
+				}
+				return []byte("Marked as read."), nil
+			},
+		},
+		{
+			Name: "mute",
+			Args: commands.Arguments{"duration"},
+			Desc: "Mute this guild, optionally for the duration only",
+			RunFunc: func(argv []string) ([]byte, error) {
+				d, err := state.ParseMuteDuration(argv)
+				if err != nil {
+					return nil, err
+				}
+
+				if err := g.state.MuteGuild(g.id, d); err != nil {
+					return nil, err
+				}
+				return []byte("Guild muted."), nil
+			},
+		},
+		{
+			Name: "unmute",
+			Desc: "Unmute this guild",
+			RunFunc: func(argv []string) ([]byte, error) {
+				if err := commands.AssertArgc(argv, 0); err != nil {
+					return nil, err
+				}
+
+				if err := g.state.UnmuteGuild(g.id); err != nil {
+					return nil, err
+				}
+				return []byte("Guild unmuted."), nil
+			},
+		},
+		{
+			Name: "notifications",
+			Args: commands.Arguments{"all|mentions|nothing"},
+			Desc: "Set the message notification level of this guild",
+			RunFunc: func(argv []string) ([]byte, error) {
+				if err := commands.AssertArgc(argv, 1); err != nil {
+					return nil, err
+				}
+
+				n, err := state.ParseNotifications(argv[0])
+				if err != nil {
+					return nil, err
+				}
+
+				if err := g.state.SetGuildNotifications(g.id, n); err != nil {
+					return nil, err
+				}
+				return []byte("Notifications set to " + argv[0] + "."), nil
+			},
+		},
+		{
+			Name: "suppress-everyone",
+			Args: commands.Arguments{"on|off"},
+			Desc: "Suppress @everyone and @here mentions in this guild",
+			RunFunc: func(argv []string) ([]byte, error) {
+				if len(argv) != 1 || (argv[0] != "on" && argv[0] != "off") {
+					return nil, errors.New("expected on or off")
+				}
+
+				if err := g.state.SuppressEveryone(g.id, argv[0] == "on"); err != nil {
+					return nil, err
+				}
+				return []byte("@everyone suppression turned " + argv[0] + "."), nil
+			},
+		},
+	}
+}
+
+// AsCommander returns the guild commands.
+func (g *Guild) AsCommander() cchat.Commander { return g }
+
+func (g *Guild) Run(words []string) ([]byte, error) {
+	return g.commands().Run(words)
+}
+
+func (g *Guild) AsCompleter() cchat.Completer { return g }
+
+func (g *Guild) Complete(words []string, i int64) []cchat.CompletionEntry {
+	return g.commands().Complete(words, i, nil)
+}
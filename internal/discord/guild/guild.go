@@ -3,13 +3,16 @@ package guild
 import (
 	"context"
 	"sort"
+	"sync"
 
 	"github.com/diamondburned/arikawa/v2/discord"
 	"github.com/diamondburned/arikawa/v2/gateway"
 	"github.com/diamondburned/cchat"
 	"github.com/diamondburned/cchat-discord/internal/discord/category"
 	"github.com/diamondburned/cchat-discord/internal/discord/channel"
+	"github.com/diamondburned/cchat-discord/internal/discord/search"
 	"github.com/diamondburned/cchat-discord/internal/discord/state"
+	"github.com/diamondburned/cchat-discord/internal/discord/voice"
 	"github.com/diamondburned/cchat-discord/internal/urlutils"
 	"github.com/diamondburned/cchat/text"
 	"github.com/diamondburned/cchat/utils/empty"
@@ -18,8 +21,12 @@ import (
 
 type Guild struct {
 	empty.Server
-	id    discord.GuildID
-	state *state.Instance
+	id     discord.GuildID
+	state  *state.Instance
+	voices voice.Channels
+
+	searchMutex sync.Mutex
+	search      *search.Server
 }
 
 func New(s *state.Instance, g *discord.Guild) cchat.Server {
@@ -58,6 +65,11 @@ func (g *Guild) Name() text.Rich {
 
 func (g *Guild) AsIconer() cchat.Iconer { return g }
 
+// AsConfigurator returns the configuration overrides for this guild.
+func (g *Guild) AsConfigurator() cchat.Configurator {
+	return g.state.Overrides.Guild(g.id)
+}
+
 func (g *Guild) Icon(ctx context.Context, iconer cchat.IconContainer) (func(), error) {
 	s, err := g.self()
 	if err != nil {
@@ -93,26 +105,70 @@ func (g *Guild) Servers(container cchat.ServersContainer) error {
 		return toplevels[i].Position < toplevels[j].Position
 	})
 
-	// Sort so that channels are before categories.
-	sort.SliceStable(toplevels, func(i, _ int) bool {
-		return toplevels[i].Type != discord.GuildCategory
+	// Sort so that text channels are before voice channels, which are before
+	// categories.
+	sort.SliceStable(toplevels, func(i, j int) bool {
+		return channelRank(toplevels[i].Type) < channelRank(toplevels[j].Type)
 	})
 
 	var chs = make([]cchat.Server, 0, len(toplevels))
+	var voices []*voice.Channel
 
 	for _, ch := range toplevels {
 		switch ch.Type {
 		case discord.GuildCategory:
 			chs = append(chs, category.New(g.state, ch))
-		case discord.GuildText:
+		case discord.GuildText, discord.GuildNews, discord.GuildStore:
 			c, err := channel.New(g.state, ch)
 			if err != nil {
+				for _, v := range voices {
+					v.Close()
+				}
+
 				return errors.Wrapf(err, "Failed to make channel %q: %v", ch.Name, err)
 			}
 			chs = append(chs, c)
+		case discord.GuildVoice, voice.GuildStageVoice:
+			v := voice.New(g.state, ch, container)
+			voices = append(voices, v)
+			chs = append(chs, v)
 		}
 	}
 
+	g.voices.Replace(voices)
+
+	// The search server always goes last.
+	chs = append(chs, g.replaceSearch())
+
 	container.SetServers(chs)
 	return nil
 }
+
+// replaceSearch closes the previously listed search server and returns a new
+// one.
+func (g *Guild) replaceSearch() *search.Server {
+	srv := search.NewServer(g.state, g.id)
+
+	g.searchMutex.Lock()
+	old := g.search
+	g.search = srv
+	g.searchMutex.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+
+	return srv
+}
+
+// channelRank returns the sorting rank of the given channel type.
+func channelRank(chType discord.ChannelType) int {
+	switch {
+	case chType == discord.GuildCategory:
+		return 2
+	case voice.IsVoice(chType):
+		return 1
+	default:
+		return 0
+	}
+}
@@ -1,13 +1,16 @@
 package message
 
 import (
+	"context"
 	"log"
 	"strings"
 	"time"
 
 	"github.com/diamondburned/arikawa/v2/discord"
 	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/arikawa/v2/utils/httputil"
 	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-discord/internal/discord/config"
 	"github.com/diamondburned/cchat-discord/internal/discord/state"
 	"github.com/diamondburned/cchat-discord/internal/segments"
 	"github.com/diamondburned/cchat-discord/internal/segments/inline"
@@ -15,6 +18,7 @@ import (
 	"github.com/diamondburned/cchat-discord/internal/segments/reference"
 	"github.com/diamondburned/cchat-discord/internal/segments/segutil"
 	"github.com/diamondburned/cchat/text"
+	"github.com/pkg/errors"
 )
 
 type messageHeader struct {
@@ -138,7 +142,7 @@ func NewAuthorUpdate(msg discord.Message, m discord.Member, s *state.Instance) M
 	user.WithMember(m)
 
 	author := NewAuthor(user)
-	if ref := ReferencedMessage(msg, s, true); ref != nil {
+	if ref := ReferencedMessage(msg, s, false); ref != nil {
 		author.AddMessageReference(*ref, s)
 	}
 
@@ -178,10 +182,10 @@ func NewMessage(m discord.Message, s *state.Instance, author Author) Message {
 
 // newMessageContent creates a new message with a content only. The given
 // message will have its ReferencedMessage field validated and filled if
-// available.
+// available in the state. ResolveReference should be used to fetch it if not.
 func newMessageContent(m *discord.Message, s *state.Instance) Message {
 	// Ensure the validity of ReferencedMessage.
-	m.ReferencedMessage = ReferencedMessage(*m, s, true)
+	m.ReferencedMessage = ReferencedMessage(*m, s, false)
 
 	var content text.Rich
 
@@ -189,10 +193,10 @@ func newMessageContent(m *discord.Message, s *state.Instance) Message {
 	case discord.ChannelPinnedMessage:
 		writeSegmented(&content, "Pinned ", "a message", " to this channel.",
 			func(i, j int) text.Segment {
-				if m.ReferencedMessage == nil {
+				if m.Reference == nil || !m.Reference.MessageID.IsValid() {
 					return nil
 				}
-				return reference.NewMessageSegment(i, j, m.ReferencedMessage.ID)
+				return reference.NewMessageSegment(i, j, m.Reference.MessageID)
 			},
 		)
 
@@ -248,8 +252,24 @@ func newMessageContent(m *discord.Message, s *state.Instance) Message {
 		content.Content = "The server is now Nitro Boosted to Tier 3."
 
 	case discord.ChannelFollowAddMessage:
-		log.Printf("[Discord] Unknown message type: %#v\n", m)
-		content.Content = "Type = discord.ChannelFollowAddMessage"
+		writeSegmented(&content, "Added ", m.Content, " to this channel.",
+			func(i, j int) text.Segment {
+				if m.Reference == nil || !m.Reference.ChannelID.IsValid() {
+					return nil
+				}
+				// The followed channel is usually in another guild, so don't
+				// bother hitting the API for it.
+				ch, err := s.Cabinet.Channel(m.Reference.ChannelID)
+				if err != nil {
+					return nil
+				}
+				return mention.Segment{
+					Start:   i,
+					End:     j,
+					Channel: mention.NewChannel(*ch),
+				}
+			},
+		)
 
 	case discord.GuildDiscoveryDisqualifiedMessage:
 		log.Printf("[Discord] Unknown message type: %#v\n", m)
@@ -266,7 +286,7 @@ func newMessageContent(m *discord.Message, s *state.Instance) Message {
 	case discord.DefaultMessage:
 		fallthrough
 	default:
-		return newRegularContent(*m, s)
+		return newRegularContent(*m, s, replyLoading)
 	}
 
 	segutil.Add(&content, inline.NewSegment(
@@ -280,12 +300,22 @@ func newMessageContent(m *discord.Message, s *state.Instance) Message {
 	}
 }
 
-func newRegularContent(m discord.Message, s *state.Instance) Message {
+const (
+	replyLoading     = "Loading replied message..."
+	replyUnavailable = "Original message was deleted."
+	replyFailed      = "Couldn't load replied message."
+)
+
+// newRegularContent renders the message content. If the message replies to a
+// message that isn't resolved, then the given placeholder is quoted instead.
+func newRegularContent(m discord.Message, s *state.Instance, placeholder string) Message {
 	var content text.Rich
+	var codeStyle = config.SyntaxHighlighting(s.Overrides)
 
-	if m.ReferencedMessage != nil {
+	switch {
+	case m.ReferencedMessage != nil:
 		refContent := []byte(m.ReferencedMessage.Content)
-		segments.ParseWithMessageRich(&content, refContent, &m, s.Cabinet)
+		segments.ParseWithMessageRich(&content, refContent, &m, s.Cabinet, codeStyle)
 
 		content = segments.Ellipsize(content, 100)
 		content.Content = ">" + content.Content + "\n"
@@ -293,9 +323,19 @@ func newRegularContent(m discord.Message, s *state.Instance) Message {
 		segutil.Add(&content,
 			reference.NewMessageSegment(0, len(content.Content)-1, m.ReferencedMessage.ID),
 		)
+
+	case needsReference(m):
+		content.Content = ">" + placeholder + "\n"
+
+		segutil.Add(&content,
+			reference.NewMessageSegment(0, len(content.Content)-1, m.Reference.MessageID),
+		)
+		segutil.Add(&content,
+			inline.NewSegment(1, len(content.Content)-1, text.AttributeDimmed),
+		)
 	}
 
-	segments.ParseMessageRich(&content, &m, s.Cabinet)
+	segments.ParseMessageRich(&content, &m, s.Cabinet, codeStyle)
 
 	return Message{
 		messageHeader: newHeaderNonce(m, m.Nonce),
@@ -353,6 +393,91 @@ func ReferencedMessage(m discord.Message, s *state.Instance, wait bool) (reply *
 	return
 }
 
+// needsReference returns true if the message is a reply that does not have its
+// referenced message.
+func needsReference(m discord.Message) bool {
+	return m.Type == discord.InlinedReplyMessage &&
+		m.ReferencedMessage == nil &&
+		m.Reference != nil &&
+		m.Reference.MessageID.IsValid()
+}
+
+// maxReferenceFetches is the maximum number of referenced messages that are
+// fetched at once.
+const maxReferenceFetches = 4
+
+// referenceFetches limits the number of concurrent referenced message fetches.
+var referenceFetches = make(chan struct{}, maxReferenceFetches)
+
+// ResolveReference fetches the message that the given message replies to in
+// the background if it's not in the state, then updates the message in the
+// container. Messages created from m show a placeholder until then. The fetch
+// is cancelled and the container is left alone once the context is done, so
+// the context should be the one that the container is bound to.
+func ResolveReference(
+	ctx context.Context, ct cchat.MessagesContainer, m discord.Message, s *state.Instance) {
+
+	if !needsReference(m) {
+		return
+	}
+
+	if ref := ReferencedMessage(m, s, false); ref != nil {
+		return
+	}
+
+	go func() {
+		select {
+		case referenceFetches <- struct{}{}:
+			defer func() { <-referenceFetches }()
+		case <-ctx.Done():
+			return
+		}
+
+		chID := m.Reference.ChannelID
+		if !chID.IsValid() {
+			chID = m.ChannelID
+		}
+
+		ref, err := s.WithContext(ctx).Message(chID, m.Reference.MessageID)
+
+		// The container might be gone by now.
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			placeholder := replyUnavailable
+			if !isNotFound(err) {
+				log.Println("[Discord] Failed to fetch replied message:", err)
+				placeholder = replyFailed
+			}
+
+			ct.UpdateMessage(Message{
+				messageHeader: newHeader(m),
+				content:       newRegularContent(m, s, placeholder).content,
+			})
+			return
+		}
+
+		// Messages fetched from the API don't have the guild ID.
+		reply := *ref
+		reply.GuildID = m.GuildID
+
+		m.ReferencedMessage = &reply
+		m.Nonce = ""
+
+		// Update the author as well to show who is being replied to.
+		ct.UpdateMessage(NewBacklogMessage(m, s))
+	}()
+}
+
+// isNotFound returns true if the error is a 404 from the API, which means the
+// message or its channel was deleted.
+func isNotFound(err error) bool {
+	var httpErr *httputil.HTTPError
+	return errors.As(err, &httpErr) && httpErr.Status == 404
+}
+
 // segmentFuncFromMention returns a function that gets the message's first
 // mention and returns a segment created from it. It returns nil if the message
 // does not have any mentions.
@@ -382,12 +507,12 @@ func writeSegmented(rich *text.Rich, start, mid, end string, f func(i, j int) te
 	var builder strings.Builder
 
 	builder.WriteString(start)
-	i, j := segutil.WriteStringBuilder(&builder, start)
+	i, j := segutil.WriteStringBuilder(&builder, mid)
 	builder.WriteString(end)
 
 	rich.Content = builder.String()
 
 	if seg := f(i, j); seg != nil {
-		rich.Segments = append(rich.Segments, f(i, j))
+		rich.Segments = append(rich.Segments, seg)
 	}
 }
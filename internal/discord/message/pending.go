@@ -0,0 +1,95 @@
+package message
+
+import (
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-discord/internal/discord/state"
+	"github.com/diamondburned/cchat-discord/internal/discord/state/outbox"
+	"github.com/diamondburned/cchat-discord/internal/segments/inline"
+	"github.com/diamondburned/cchat-discord/internal/segments/mention"
+	"github.com/diamondburned/cchat-discord/internal/segments/segutil"
+	"github.com/diamondburned/cchat/text"
+)
+
+// Pending is a message that is queued in the outbox. Its nonce is the one given
+// by the frontend, so it can be matched with the message that was sent.
+type Pending struct {
+	entry   outbox.Entry
+	author  *Author
+	content text.Rich
+}
+
+var (
+	_ cchat.MessageCreate = (*Pending)(nil)
+	_ cchat.MessageUpdate = (*Pending)(nil)
+	_ cchat.MessageDelete = (*Pending)(nil)
+)
+
+// NewPending creates a new pending message from the outbox entry. The content
+// has the sending status appended to it.
+func NewPending(e outbox.Entry, guildID discord.GuildID, s *state.Instance) Pending {
+	var author *Author
+
+	if me, err := s.Cabinet.Me(); err == nil {
+		user := mention.NewUser(*me)
+		user.WithGuildID(guildID)
+		user.WithState(s.State)
+		user.Prefetch()
+
+		a := NewAuthor(user)
+		author = &a
+	}
+
+	var content text.Rich
+	segutil.Write(&content, e.Data.Content)
+
+	for _, file := range e.Data.Files {
+		segutil.Write(&content, "\n"+file.Name)
+	}
+
+	start, end := segutil.Write(&content, "\n"+PendingStatus(e))
+	segutil.Add(&content, inline.NewSegment(start+1, end, text.AttributeDimmed))
+
+	return Pending{
+		entry:   e,
+		author:  author,
+		content: content,
+	}
+}
+
+// NewPendingDelete creates a pending message used only to delete it.
+func NewPendingDelete(e outbox.Entry) Pending {
+	return Pending{entry: e}
+}
+
+// PendingStatus describes the sending status of the entry.
+func PendingStatus(e outbox.Entry) string {
+	switch e.Status {
+	case outbox.Sending:
+		return "Sending..."
+	case outbox.Failed:
+		return "Failed to send: " + e.Err.Error()
+	}
+
+	retry := time.Until(e.RetryAt).Round(time.Second)
+	if retry < time.Second {
+		return "Failed to send, retrying..."
+	}
+
+	return "Failed to send, retrying in " + retry.String() + "..."
+}
+
+func (p Pending) ID() cchat.ID       { return outbox.ID(p.entry.Nonce) }
+func (p Pending) Time() time.Time    { return p.entry.Created }
+func (p Pending) Nonce() string      { return p.entry.Original }
+func (p Pending) Mentioned() bool    { return false }
+func (p Pending) Content() text.Rich { return p.content }
+
+func (p Pending) Author() cchat.Author {
+	if p.author == nil {
+		return nil
+	}
+	return *p.author
+}
@@ -0,0 +1,69 @@
+package message
+
+import (
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-discord/internal/discord/state"
+	"github.com/diamondburned/cchat-discord/internal/segments/timestamp"
+)
+
+// TimestampRefreshInterval is how often messages with relative timestamps are
+// rendered again.
+const TimestampRefreshInterval = time.Minute
+
+// RefreshTimestamps periodically updates the messages that have relative
+// timestamps, such as "5 minutes ago", so that they stay accurate. The
+// messages are taken from the given function every time. The returned
+// function stops refreshing.
+func RefreshTimestamps(
+	ct cchat.MessagesContainer, s *state.Instance, messages func() []discord.Message) func() {
+
+	var ticker = time.NewTicker(TimestampRefreshInterval)
+	var done = make(chan struct{})
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			for _, m := range messages() {
+				if HasRelativeTimestamps(m) {
+					ct.UpdateMessage(NewContentUpdate(m, s))
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// HasRelativeTimestamps returns true if the message content or its embeds
+// have relative timestamps.
+func HasRelativeTimestamps(m discord.Message) bool {
+	if timestamp.HasRelative(m.Content) {
+		return true
+	}
+
+	for _, embed := range m.Embeds {
+		if timestamp.HasRelative(embed.Description) {
+			return true
+		}
+
+		for _, field := range embed.Fields {
+			if timestamp.HasRelative(field.Value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
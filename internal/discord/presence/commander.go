@@ -0,0 +1,103 @@
+package presence
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/cchat-discord/internal/discord/channel/commands"
+)
+
+// Commands returns the status commands.
+func (m *Manager) Commands() commands.Commands {
+	return commands.Commands{
+		{
+			Name: "status",
+			Args: commands.Arguments{strings.Join(statusNames(), "|")},
+			Desc: "Print or set the status",
+			RunFunc: func(argv []string) ([]byte, error) {
+				if len(argv) == 0 {
+					return []byte(describe(m.Status(), m.CustomStatus())), nil
+				}
+
+				status, err := ParseStatus(strings.Join(argv, " "))
+				if err != nil {
+					return nil, err
+				}
+
+				if err := m.SetStatus(status); err != nil {
+					return nil, err
+				}
+
+				return []byte("Status set to " + string(status) + "."), nil
+			},
+		},
+		{
+			Name: "custom-status",
+			Args: commands.Arguments{"-e emoji", "-d duration", "text"},
+			Desc: "Set the custom status, optionally expiring after the duration",
+			RunFunc: func(argv []string) ([]byte, error) {
+				var custom CustomStatus
+				var expiry time.Duration
+
+				fs := flag.NewFlagSet("custom-status", 0)
+				fs.SetOutput(ioutil.Discard)
+				fs.StringVar(&custom.Emoji, "e", "", "Emoji")
+				fs.DurationVar(&expiry, "d", 0, "Duration")
+
+				if err := fs.Parse(argv); err != nil {
+					return nil, err
+				}
+
+				custom.Text = strings.Join(fs.Args(), " ")
+				if custom.IsZero() {
+					return nil, fmt.Errorf("missing text or emoji, use clear-status to clear")
+				}
+
+				if expiry > 0 {
+					custom.ExpiresAt = time.Now().Add(expiry)
+				}
+
+				if err := m.SetCustomStatus(custom); err != nil {
+					return nil, err
+				}
+
+				return []byte("Custom status set."), nil
+			},
+		},
+		{
+			Name: "clear-status",
+			Desc: "Clear the custom status",
+			RunFunc: func(argv []string) ([]byte, error) {
+				if err := commands.AssertArgc(argv, 0); err != nil {
+					return nil, err
+				}
+
+				if err := m.SetCustomStatus(CustomStatus{}); err != nil {
+					return nil, err
+				}
+
+				return []byte("Custom status cleared."), nil
+			},
+		},
+	}
+}
+
+func describe(status gateway.Status, custom CustomStatus) string {
+	var builder strings.Builder
+	builder.WriteString("Status: " + string(status))
+
+	if !custom.IsZero() {
+		builder.WriteString("\nCustom status: ")
+		builder.WriteString(strings.TrimSpace(custom.Emoji + " " + custom.Text))
+
+		if !custom.ExpiresAt.IsZero() {
+			builder.WriteString(" (until " + custom.ExpiresAt.Format(time.Kitchen) + ")")
+		}
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,124 @@
+package presence
+
+import (
+	"time"
+
+	"github.com/diamondburned/cchat"
+	"github.com/pkg/errors"
+)
+
+// Configuration keys.
+const (
+	StatusKey       = "Status"
+	CustomTextKey   = "Custom Status"
+	CustomEmojiKey  = "Custom Status Emoji"
+	CustomExpiryKey = "Custom Status Expiry"
+)
+
+// Configurator returns the status as a configurator. The expiry is the
+// duration from now, such as 30m or 4h; it is empty if the custom status
+// doesn't expire.
+func (m *Manager) Configurator() cchat.Configurator {
+	return configurator{m}
+}
+
+type configurator struct {
+	m *Manager
+}
+
+func (c configurator) Configuration() (map[string]string, error) {
+	custom := c.m.CustomStatus()
+
+	var expiry string
+	if !custom.ExpiresAt.IsZero() {
+		expiry = time.Until(custom.ExpiresAt).Round(time.Minute).String()
+	}
+
+	return map[string]string{
+		StatusKey:       string(c.m.Status()),
+		CustomTextKey:   custom.Text,
+		CustomEmojiKey:  custom.Emoji,
+		CustomExpiryKey: expiry,
+	}, nil
+}
+
+// SetConfiguration applies the fields that changed. Missing fields are kept
+// as-is.
+func (c configurator) SetConfiguration(config map[string]string) error {
+	if v, ok := config[StatusKey]; ok {
+		status, err := ParseStatus(v)
+		if err != nil {
+			return &cchat.ErrInvalidConfigAtField{Key: StatusKey, Err: err}
+		}
+
+		if status != c.m.Status() {
+			if err := c.m.SetStatus(status); err != nil {
+				return err
+			}
+		}
+	}
+
+	old := c.m.CustomStatus()
+	custom := old
+
+	if v, ok := config[CustomTextKey]; ok {
+		custom.Text = v
+	}
+
+	if v, ok := config[CustomEmojiKey]; ok {
+		custom.Emoji = v
+		if _, err := custom.emoji(); err != nil {
+			return &cchat.ErrInvalidConfigAtField{Key: CustomEmojiKey, Err: err}
+		}
+	}
+
+	if v, ok := config[CustomExpiryKey]; ok {
+		expiresAt, err := parseExpiry(v, old.ExpiresAt)
+		if err != nil {
+			return &cchat.ErrInvalidConfigAtField{Key: CustomExpiryKey, Err: err}
+		}
+		custom.ExpiresAt = expiresAt
+	}
+
+	if custom.IsZero() {
+		custom = CustomStatus{}
+	}
+
+	if custom == old {
+		return nil
+	}
+
+	return c.m.SetCustomStatus(custom)
+}
+
+// parseExpiry parses the expiry duration into the time. The old time is kept if
+// the value is what Configuration would have returned for it.
+func parseExpiry(v string, old time.Time) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if d <= 0 {
+		return time.Time{}, errors.New("expiry must be positive")
+	}
+
+	if !old.IsZero() && time.Until(old).Round(time.Minute) == d {
+		return old, nil
+	}
+
+	return time.Now().Add(d), nil
+}
+
+// statusNames returns the names of the settable statuses.
+func statusNames() []string {
+	names := make([]string, len(Statuses))
+	for i, status := range Statuses {
+		names[i] = string(status)
+	}
+	return names
+}
@@ -0,0 +1,302 @@
+// Package presence manages the user's own status and custom status. Changes
+// are saved into the user settings, so that other clients pick them up, and
+// sent to the gateway.
+package presence
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/api"
+	"github.com/diamondburned/arikawa/v2/bot/extras/arguments"
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/arikawa/v2/utils/httputil"
+	"github.com/diamondburned/cchat-discord/internal/discord/state"
+	"github.com/diamondburned/cchat-discord/internal/funcutil"
+	"github.com/diamondburned/ningen/v2"
+	"github.com/pkg/errors"
+)
+
+// EndpointSettings is the endpoint of the user settings.
+var EndpointSettings = api.EndpointMe + "/settings"
+
+// Statuses is the list of statuses that can be set.
+var Statuses = []gateway.Status{
+	gateway.OnlineStatus,
+	gateway.IdleStatus,
+	gateway.DoNotDisturbStatus,
+	gateway.InvisibleStatus,
+}
+
+// ParseStatus parses the status name. It is case-insensitive.
+func ParseStatus(str string) (gateway.Status, error) {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "online":
+		return gateway.OnlineStatus, nil
+	case "idle", "away":
+		return gateway.IdleStatus, nil
+	case "dnd", "busy", "do not disturb":
+		return gateway.DoNotDisturbStatus, nil
+	case "invisible", "offline":
+		return gateway.InvisibleStatus, nil
+	default:
+		return "", errors.Errorf("unknown status %q, must be one of online, idle, dnd or invisible", str)
+	}
+}
+
+// CustomStatus is the custom status shown under the user's name. The zero
+// value means no custom status.
+type CustomStatus struct {
+	Text string
+	// Emoji is either a Unicode emoji or a custom emoji formatted as
+	// <:name:id>.
+	Emoji string
+	// ExpiresAt is zero if the status doesn't expire.
+	ExpiresAt time.Time
+}
+
+// IsZero returns true if there is no custom status.
+func (cs CustomStatus) IsZero() bool {
+	return cs.Text == "" && cs.Emoji == ""
+}
+
+// Expired returns true if the custom status has expired at the given time.
+func (cs CustomStatus) Expired(now time.Time) bool {
+	return !cs.ExpiresAt.IsZero() && !now.Before(cs.ExpiresAt)
+}
+
+func (cs CustomStatus) emoji() (*arguments.Emoji, error) {
+	if cs.Emoji == "" {
+		return nil, nil
+	}
+
+	var emoji arguments.Emoji
+	if err := emoji.Parse(cs.Emoji); err != nil {
+		return nil, errors.Wrap(err, "invalid custom status emoji")
+	}
+
+	return &emoji, nil
+}
+
+// customStatusData is the custom status in the user settings.
+type customStatusData struct {
+	Text      string            `json:"text,omitempty"`
+	ExpiresAt discord.Timestamp `json:"expires_at"`
+	EmojiID   discord.EmojiID   `json:"emoji_id,omitempty"`
+	EmojiName string            `json:"emoji_name,omitempty"`
+}
+
+func customStatusFromSettings(cs *gateway.CustomUserStatus) CustomStatus {
+	if cs == nil {
+		return CustomStatus{}
+	}
+
+	var status = CustomStatus{
+		Text:      cs.Text,
+		ExpiresAt: cs.ExpiresAt.Time(),
+		Emoji:     cs.EmojiName,
+	}
+
+	if cs.EmojiID.IsValid() {
+		status.Emoji = "<:" + cs.EmojiName + ":" + cs.EmojiID.String() + ">"
+	}
+
+	return status
+}
+
+// Manager keeps track of the user's status.
+type Manager struct {
+	state *state.Instance
+
+	mutex  sync.Mutex
+	status gateway.Status
+	custom CustomStatus
+	expiry *time.Timer
+
+	cancel func()
+}
+
+func New(s *state.Instance) *Manager {
+	m := &Manager{
+		state:  s,
+		status: gateway.OnlineStatus,
+	}
+
+	if settings := s.Ready().UserSettings; settings != nil {
+		m.apply(settings.Status, customStatusFromSettings(settings.CustomStatus))
+	}
+
+	m.cancel = funcutil.JoinCancels(
+		s.AddHandler(func(ev *gateway.UserSettingsUpdateEvent) {
+			// The event only has the settings that changed, so a missing
+			// custom status could either mean that it was cleared or that it
+			// wasn't touched. Fetch the settings to know which.
+			if ev.CustomStatus != nil {
+				m.apply(ev.Status, customStatusFromSettings(ev.CustomStatus))
+				m.sendPresence()
+				return
+			}
+
+			go m.refresh(ev.Status)
+		}),
+		s.AddHandler(func(*ningen.Connected) {
+			m.sendPresence()
+		}),
+	)
+
+	return m
+}
+
+// Close unbinds the handlers and stops the expiry timer.
+func (m *Manager) Close() {
+	m.cancel()
+
+	m.mutex.Lock()
+	if m.expiry != nil {
+		m.expiry.Stop()
+	}
+	m.mutex.Unlock()
+}
+
+// Status returns the current status.
+func (m *Manager) Status() gateway.Status {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.status
+}
+
+// CustomStatus returns the current custom status.
+func (m *Manager) CustomStatus() CustomStatus {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.custom
+}
+
+// SetStatus saves the status into the user settings and updates the presence.
+func (m *Manager) SetStatus(status gateway.Status) error {
+	body := map[string]interface{}{"status": status}
+
+	if err := m.state.FastRequest("PATCH", EndpointSettings, httputil.WithJSONBody(body)); err != nil {
+		return errors.Wrap(err, "failed to save status")
+	}
+
+	m.apply(status, m.CustomStatus())
+	return m.sendPresence()
+}
+
+// SetCustomStatus saves the custom status into the user settings and updates
+// the presence. The zero value clears the custom status.
+func (m *Manager) SetCustomStatus(cs CustomStatus) error {
+	var data *customStatusData
+
+	if !cs.IsZero() {
+		emoji, err := cs.emoji()
+		if err != nil {
+			return err
+		}
+
+		data = &customStatusData{
+			Text:      cs.Text,
+			ExpiresAt: discord.NewTimestamp(cs.ExpiresAt),
+		}
+
+		if emoji != nil {
+			data.EmojiID = emoji.ID
+			data.EmojiName = emoji.Name
+		}
+	}
+
+	body := map[string]interface{}{"custom_status": data}
+
+	if err := m.state.FastRequest("PATCH", EndpointSettings, httputil.WithJSONBody(body)); err != nil {
+		return errors.Wrap(err, "failed to save custom status")
+	}
+
+	m.apply("", cs)
+	return m.sendPresence()
+}
+
+// refresh fetches the user settings and applies the custom status from it.
+func (m *Manager) refresh(status gateway.Status) {
+	var settings gateway.UserSettings
+
+	if err := m.state.RequestJSON(&settings, "GET", EndpointSettings); err != nil {
+		log.Println("[Discord] Failed to fetch user settings:", err)
+		m.apply(status, m.CustomStatus())
+	} else {
+		m.apply(settings.Status, customStatusFromSettings(settings.CustomStatus))
+	}
+
+	m.sendPresence()
+}
+
+// apply sets the status and custom status. An empty status is ignored.
+func (m *Manager) apply(status gateway.Status, cs CustomStatus) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if status != "" {
+		m.status = status
+	}
+
+	m.custom = cs
+
+	if m.expiry != nil {
+		m.expiry.Stop()
+		m.expiry = nil
+	}
+
+	// Drop the custom status from the presence once it expires.
+	if !cs.ExpiresAt.IsZero() {
+		m.expiry = time.AfterFunc(time.Until(cs.ExpiresAt), func() {
+			m.mutex.Lock()
+			if m.custom == cs {
+				m.custom = CustomStatus{}
+			}
+			m.mutex.Unlock()
+
+			m.sendPresence()
+		})
+	}
+}
+
+// sendPresence sends the current status to the gateway.
+func (m *Manager) sendPresence() error {
+	m.mutex.Lock()
+	data := gateway.UpdateStatusData{
+		Status:     m.status,
+		Activities: &[]discord.Activity{},
+	}
+	custom := m.custom
+	m.mutex.Unlock()
+
+	if !custom.IsZero() && !custom.Expired(time.Now()) {
+		activity := discord.Activity{
+			Name:  "Custom Status",
+			Type:  discord.CustomActivity,
+			State: custom.Text,
+		}
+
+		if emoji, _ := custom.emoji(); emoji != nil {
+			activity.Emoji = &discord.Emoji{
+				ID:       emoji.ID,
+				Name:     emoji.Name,
+				Animated: emoji.Animated,
+			}
+		}
+
+		*data.Activities = append(*data.Activities, activity)
+	}
+
+	if err := m.state.Gateway.UpdateStatus(data); err != nil {
+		log.Println("[Discord] Failed to update presence:", err)
+		return errors.Wrap(err, "failed to update presence")
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package presence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+)
+
+func TestParseStatus(t *testing.T) {
+	var tests = map[string]gateway.Status{
+		"online":         gateway.OnlineStatus,
+		"Idle":           gateway.IdleStatus,
+		"do not disturb": gateway.DoNotDisturbStatus,
+		" invisible ":    gateway.InvisibleStatus,
+	}
+
+	for input, expect := range tests {
+		if got, err := ParseStatus(input); err != nil || got != expect {
+			t.Errorf("ParseStatus(%q) = %q, %v; expected %q", input, got, err, expect)
+		}
+	}
+
+	if _, err := ParseStatus("away from keyboard"); err == nil {
+		t.Error("Expected an error for an unknown status")
+	}
+}
+
+func TestCustomStatusFromSettings(t *testing.T) {
+	expires := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cs := customStatusFromSettings(&gateway.CustomUserStatus{
+		Text:      "busy",
+		ExpiresAt: discord.NewTimestamp(expires),
+		EmojiID:   123,
+		EmojiName: "pog",
+	})
+
+	expect := CustomStatus{Text: "busy", Emoji: "<:pog:123>", ExpiresAt: expires}
+	if cs != expect {
+		t.Fatalf("Unexpected custom status %#v", cs)
+	}
+
+	if !cs.Expired(expires) || cs.Expired(expires.Add(-time.Second)) {
+		t.Fatal("Unexpected expiry")
+	}
+
+	if emoji, err := cs.emoji(); err != nil || emoji.ID != 123 || emoji.Name != "pog" {
+		t.Fatalf("Unexpected emoji %#v, %v", emoji, err)
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	if v, err := parseExpiry("", time.Now()); err != nil || !v.IsZero() {
+		t.Fatal("Empty expiry should clear the time")
+	}
+
+	old := time.Now().Add(time.Hour)
+	if v, err := parseExpiry("1h0m0s", old); err != nil || !v.Equal(old) {
+		t.Fatal("Unchanged expiry should keep the old time")
+	}
+
+	if _, err := parseExpiry("-5m", time.Time{}); err == nil {
+		t.Fatal("Expected an error for a negative expiry")
+	}
+}
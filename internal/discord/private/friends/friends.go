@@ -0,0 +1,210 @@
+// Package friends provides the server that lists the user's relationships:
+// friends, pending friend requests and blocked users.
+package friends
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-discord/internal/discord/private/hub"
+	"github.com/diamondburned/cchat-discord/internal/discord/state"
+	"github.com/diamondburned/cchat-discord/internal/funcutil"
+	"github.com/diamondburned/cchat/text"
+	"github.com/diamondburned/cchat/utils/empty"
+)
+
+// sections is the order that the relationship types are listed in.
+var sections = []discord.RelationshipType{
+	discord.IncomingFriendRequest,
+	discord.FriendRelationship,
+	discord.SentFriendRequest,
+	discord.BlockedRelationship,
+}
+
+func sectionName(t discord.RelationshipType) string {
+	switch t {
+	case discord.IncomingFriendRequest:
+		return "Incoming Requests"
+	case discord.FriendRelationship:
+		return "Friends"
+	case discord.SentFriendRequest:
+		return "Outgoing Requests"
+	case discord.BlockedRelationship:
+		return "Blocked"
+	default:
+		return "Unknown"
+	}
+}
+
+// Server is the Friends server. It lists a nested server for each kind of
+// relationship, and keeps them updated as relationships and presences change.
+type Server struct {
+	empty.Server
+	state *state.Instance
+	adder hub.ChannelAdder
+
+	mutex sync.Mutex
+	// container is the last container given to Servers.
+	container cchat.ServersContainer
+	// sections has the last container of each section.
+	sections map[discord.RelationshipType]cchat.ServersContainer
+
+	cancel func()
+}
+
+func New(s *state.Instance, adder hub.ChannelAdder) *Server {
+	friends := &Server{
+		state:    s,
+		adder:    adder,
+		sections: map[discord.RelationshipType]cchat.ServersContainer{},
+	}
+
+	friends.cancel = funcutil.JoinCancels(
+		s.AddHandler(func(*gateway.RelationshipAddEvent) {
+			friends.reset()
+		}),
+		s.AddHandler(func(*gateway.RelationshipRemoveEvent) {
+			friends.reset()
+		}),
+		s.AddHandler(func(p *gateway.PresenceUpdateEvent) {
+			if s.RelationshipState.Relationship(p.User.ID) != 0 {
+				friends.updatePresence(p.User.ID)
+			}
+		}),
+	)
+
+	return friends
+}
+
+func (friends *Server) ID() cchat.ID { return "!!!friends-server!!!" }
+
+func (friends *Server) Name() text.Rich { return text.Plain("Friends") }
+
+// Close unbinds the handlers, invalidating the server forever.
+func (friends *Server) Close() { friends.cancel() }
+
+func (friends *Server) AsLister() cchat.Lister { return friends }
+
+func (friends *Server) Servers(container cchat.ServersContainer) error {
+	friends.mutex.Lock()
+	friends.container = container
+	friends.mutex.Unlock()
+
+	container.SetServers(friends.sectionServers())
+	return nil
+}
+
+// sectionServers returns the servers of the sections that aren't empty.
+func (friends *Server) sectionServers() []cchat.Server {
+	var counts = map[discord.RelationshipType]int{}
+
+	friends.state.RelationshipState.Each(func(r *discord.Relationship) bool {
+		counts[r.Type]++
+		return false
+	})
+
+	var servers []cchat.Server
+
+	for _, t := range sections {
+		if counts[t] > 0 {
+			servers = append(servers, &section{friends: friends, typ: t})
+		}
+	}
+
+	return servers
+}
+
+// reset lists all sections again after a relationship is added or removed.
+func (friends *Server) reset() {
+	friends.mutex.Lock()
+	// The old section containers are thrown away by the frontend.
+	friends.sections = map[discord.RelationshipType]cchat.ServersContainer{}
+	container := friends.container
+	friends.mutex.Unlock()
+
+	// Call the container outside the lock, since the frontend may call Servers
+	// on the sections right away.
+	if container != nil {
+		container.SetServers(friends.sectionServers())
+	}
+}
+
+// updatePresence replaces the user's server so the name has the new status.
+func (friends *Server) updatePresence(userID discord.UserID) {
+	var rel *discord.Relationship
+
+	friends.state.RelationshipState.Each(func(r *discord.Relationship) bool {
+		if r.UserID == userID {
+			copied := *r
+			rel = &copied
+			return true
+		}
+		return false
+	})
+
+	if rel == nil {
+		return
+	}
+
+	friends.mutex.Lock()
+	container := friends.sections[rel.Type]
+	friends.mutex.Unlock()
+
+	if container != nil {
+		container.UpdateServer(replaceServer{
+			Server: NewRelationship(friends.state, friends.adder, *rel),
+		})
+	}
+}
+
+type replaceServer struct{ cchat.Server }
+
+func (rs replaceServer) PreviousID() (cchat.ID, bool) { return rs.Server.ID(), true }
+
+type section struct {
+	empty.Server
+	friends *Server
+	typ     discord.RelationshipType
+}
+
+func (sect *section) ID() cchat.ID {
+	return "!!!friends-" + strconv.Itoa(int(sect.typ)) + "!!!"
+}
+
+func (sect *section) Name() text.Rich { return text.Plain(sectionName(sect.typ)) }
+
+func (sect *section) AsLister() cchat.Lister { return sect }
+
+func (sect *section) Servers(container cchat.ServersContainer) error {
+	s := sect.friends.state
+
+	var rels []discord.Relationship
+
+	s.RelationshipState.Each(func(r *discord.Relationship) bool {
+		if r.Type == sect.typ {
+			rels = append(rels, *r)
+		}
+		return false
+	})
+
+	sort.Slice(rels, func(i, j int) bool {
+		return strings.ToLower(rels[i].User.Username) < strings.ToLower(rels[j].User.Username)
+	})
+
+	servers := make([]cchat.Server, len(rels))
+	for i, rel := range rels {
+		servers[i] = NewRelationship(s, sect.friends.adder, rel)
+	}
+
+	sect.friends.mutex.Lock()
+	sect.friends.sections[sect.typ] = container
+	sect.friends.mutex.Unlock()
+
+	container.SetServers(servers)
+	return nil
+}
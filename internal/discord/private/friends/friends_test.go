@@ -0,0 +1,103 @@
+package friends_test
+
+import (
+	"testing"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/cchat-discord/internal/discord/private/friends"
+	"github.com/diamondburned/cchat-discord/internal/discord/state"
+	"github.com/diamondburned/cchat-discord/internal/discord/state/replay"
+)
+
+type channelAdder struct{ added []discord.ChannelID }
+
+func (a *channelAdder) AddChannel(_ *state.Instance, ch *discord.Channel) {
+	a.added = append(a.added, ch.ID)
+}
+
+func newHarness(t *testing.T) *replay.Harness {
+	t.Helper()
+
+	f, err := replay.Load("../../state/replay/testdata/basic.json")
+	if err != nil {
+		t.Fatal("Failed to load fixture:", err)
+	}
+
+	f.REST = append(f.REST, replay.Response{
+		Method: "PUT",
+		Path:   "/users/@me/relationships/3",
+	})
+
+	h, err := replay.New(f)
+	if err != nil {
+		t.Fatal("Failed to create harness:", err)
+	}
+
+	t.Cleanup(h.Close)
+	return h
+}
+
+func TestRelationships(t *testing.T) {
+	h := newHarness(t)
+
+	server := friends.New(h.Instance, &channelAdder{})
+	defer server.Close()
+
+	sections := &replay.ServersContainer{}
+	if err := server.Servers(sections); err != nil {
+		t.Fatal("Failed to list sections:", err)
+	}
+
+	if len(sections.Servers) != 0 {
+		t.Fatalf("Expected no sections, got %d", len(sections.Servers))
+	}
+
+	rel := discord.Relationship{
+		UserID: 3,
+		User:   discord.User{ID: 3, Username: "stranger"},
+		Type:   discord.IncomingFriendRequest,
+	}
+
+	if err := h.Dispatch("RELATIONSHIP_ADD", rel); err != nil {
+		t.Fatal("Failed to dispatch:", err)
+	}
+
+	if len(sections.Servers) != 1 {
+		t.Fatalf("Expected 1 section after the request, got %d", len(sections.Servers))
+	}
+
+	if name := sections.Servers[0].Name().Content; name != "Incoming Requests" {
+		t.Fatalf("Unexpected section %q", name)
+	}
+
+	requests := &replay.ServersContainer{}
+	if err := sections.Servers[0].AsLister().Servers(requests); err != nil {
+		t.Fatal("Failed to list requests:", err)
+	}
+
+	if len(requests.Servers) != 1 || requests.Servers[0].ID() != "3" {
+		t.Fatalf("Expected the request of user 3, got %#v", requests.Servers)
+	}
+
+	if _, err := requests.Servers[0].AsCommander().Run([]string{"accept"}); err != nil {
+		t.Fatal("Failed to accept:", err)
+	}
+
+	var accepted bool
+	for _, r := range h.Requests() {
+		if r.Method == "PUT" && r.Path == "/users/@me/relationships/3" {
+			accepted = true
+		}
+	}
+	if !accepted {
+		t.Fatalf("Request was never accepted: %#v", h.Requests())
+	}
+
+	if err := h.Dispatch("RELATIONSHIP_REMOVE", rel); err != nil {
+		t.Fatal("Failed to dispatch:", err)
+	}
+
+	if len(sections.Servers) != 0 {
+		t.Fatalf("Expected no sections after the removal, got %d", len(sections.Servers))
+	}
+}
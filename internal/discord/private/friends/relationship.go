@@ -0,0 +1,175 @@
+package friends
+
+import (
+	"context"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-discord/internal/discord/channel/commands"
+	"github.com/diamondburned/cchat-discord/internal/discord/private/hub"
+	"github.com/diamondburned/cchat-discord/internal/discord/state"
+	"github.com/diamondburned/cchat-discord/internal/segments/colored"
+	"github.com/diamondburned/cchat-discord/internal/segments/inline"
+	"github.com/diamondburned/cchat-discord/internal/segments/segutil"
+	"github.com/diamondburned/cchat-discord/internal/urlutils"
+	"github.com/diamondburned/cchat/text"
+	"github.com/diamondburned/cchat/utils/empty"
+	"github.com/pkg/errors"
+)
+
+// Status colors, same as the official client.
+const (
+	onlineColor  = 0x43B581
+	idleColor    = 0xFAA61A
+	busyColor    = 0xF04747
+	offlineColor = 0x747F8D
+)
+
+func statusColor(status gateway.Status) uint32 {
+	switch status {
+	case gateway.OnlineStatus:
+		return onlineColor
+	case gateway.IdleStatus:
+		return idleColor
+	case gateway.DoNotDisturbStatus:
+		return busyColor
+	default:
+		return offlineColor
+	}
+}
+
+// Relationship is a server that represents a single user in the Friends list.
+// Its actions are exposed as commands.
+type Relationship struct {
+	empty.Server
+	state *state.Instance
+	adder hub.ChannelAdder
+	rel   discord.Relationship
+}
+
+var _ cchat.Server = (*Relationship)(nil)
+
+func NewRelationship(s *state.Instance, adder hub.ChannelAdder, rel discord.Relationship) *Relationship {
+	return &Relationship{
+		state: s,
+		adder: adder,
+		rel:   rel,
+	}
+}
+
+func (r *Relationship) ID() cchat.ID {
+	return r.rel.UserID.String()
+}
+
+// Name returns the username. Friends are colored by their status.
+func (r *Relationship) Name() text.Rich {
+	var rich text.Rich
+
+	start, end := segutil.Write(&rich, r.rel.User.Username)
+
+	switch r.rel.Type {
+	case discord.FriendRelationship:
+		var status gateway.Status
+		if p, err := r.state.Presence(0, r.rel.UserID); err == nil {
+			status = p.Status
+		}
+
+		segutil.Add(&rich, colored.NewSegment(start, end, statusColor(status)))
+
+	case discord.BlockedRelationship:
+		segutil.Add(&rich, inline.NewSegment(start, end, text.AttributeDimmed))
+	}
+
+	start, end = segutil.Write(&rich, "#"+r.rel.User.Discriminator)
+	segutil.Add(&rich, inline.NewSegment(start, end, text.AttributeDimmed))
+
+	return rich
+}
+
+func (r *Relationship) AsIconer() cchat.Iconer { return r }
+
+func (r *Relationship) Icon(ctx context.Context, iconer cchat.IconContainer) (func(), error) {
+	iconer.SetIcon(urlutils.AvatarURL(r.rel.User.AvatarURL()))
+	return func() {}, nil
+}
+
+func (r *Relationship) AsCommander() cchat.Commander { return r }
+
+// command returns a command that runs fn on the relationship.
+func (r *Relationship) command(name, desc string, fn func(r *Relationship) error) commands.Command {
+	return commands.Command{
+		Name: name,
+		Desc: desc,
+		RunFunc: func(argv []string) ([]byte, error) {
+			if err := commands.AssertArgc(argv, 0); err != nil {
+				return nil, err
+			}
+
+			if err := fn(r); err != nil {
+				return nil, errors.Wrapf(err, "failed to %s", name)
+			}
+			return nil, nil
+		},
+	}
+}
+
+func acceptRequest(r *Relationship) error {
+	return r.state.SetRelationship(r.rel.UserID, discord.FriendRelationship)
+}
+
+func deleteRelationship(r *Relationship) error {
+	return r.state.DeleteRelationship(r.rel.UserID)
+}
+
+func blockUser(r *Relationship) error {
+	return r.state.SetRelationship(r.rel.UserID, discord.BlockedRelationship)
+}
+
+func openDM(r *Relationship) error {
+	ch, err := r.state.CreatePrivateChannel(r.rel.UserID)
+	if err != nil {
+		return err
+	}
+
+	r.adder.AddChannel(r.state, ch)
+	return nil
+}
+
+// commands returns the commands that apply to the relationship's type. The
+// relationship lists are updated by the gateway events that follow.
+func (r *Relationship) commands() commands.Commands {
+	var (
+		accept  = r.command("accept", "Accept the friend request", acceptRequest)
+		decline = r.command("decline", "Decline the friend request", deleteRelationship)
+		cancel  = r.command("cancel", "Cancel the friend request", deleteRelationship)
+		remove  = r.command("remove", "Remove the friend", deleteRelationship)
+		unblock = r.command("unblock", "Unblock the user", deleteRelationship)
+		block   = r.command("block", "Block the user", blockUser)
+		dm      = r.command("dm", "Open a direct message with the user", openDM)
+	)
+
+	switch r.rel.Type {
+	case discord.IncomingFriendRequest:
+		return commands.Commands{accept, decline, block, dm}
+	case discord.SentFriendRequest:
+		return commands.Commands{cancel, block, dm}
+	case discord.FriendRelationship:
+		return commands.Commands{dm, remove, block}
+	case discord.BlockedRelationship:
+		return commands.Commands{unblock}
+	default:
+		return nil
+	}
+}
+
+// Run runs one of the relationship commands.
+func (r *Relationship) Run(words []string) ([]byte, error) {
+	return r.commands().Run(words)
+}
+
+func (r *Relationship) AsCompleter() cchat.Completer { return r }
+
+func (r *Relationship) Complete(words []string, i int64) []cchat.CompletionEntry {
+	return r.commands().Complete(words, i, nil)
+}
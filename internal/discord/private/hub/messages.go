@@ -148,6 +148,7 @@ func (msgs *Messages) JoinServer(ctx context.Context, ct cchat.MessagesContainer
 
 	for _, msg := range msgs.messages {
 		ct.CreateMessage(message.NewDirectMessage(msg, msgs.state))
+		message.ResolveReference(ctx, ct, msg, msgs.state)
 	}
 
 	msgs.msgMutex.Unlock()
@@ -179,7 +180,10 @@ func (msgs *Messages) JoinServer(ctx context.Context, ct cchat.MessagesContainer
 			}
 
 			ct.CreateMessage(message.NewMessage(msg.Message, msgs.state, author))
-			msgs.state.ReadState.MarkRead(msg.ChannelID, msg.ID)
+			if !isReply {
+				message.ResolveReference(ctx, ct, msg.Message, msgs.state)
+			}
+			msgs.state.Unread.AutoMarkRead(msg.ChannelID, msg.ID)
 		}),
 		msgs.state.AddHandler(func(update *gateway.MessageUpdateEvent) {
 			if update.GuildID.IsValid() || msgs.acList.isActive(update.ChannelID) {
@@ -195,6 +199,12 @@ func (msgs *Messages) JoinServer(ctx context.Context, ct cchat.MessagesContainer
 
 			ct.DeleteMessage(message.NewHeaderDelete(del))
 		}),
+		message.RefreshTimestamps(ct, msgs.state, func() []discord.Message {
+			msgs.msgMutex.Lock()
+			defer msgs.msgMutex.Unlock()
+
+			return append([]discord.Message(nil), msgs.messages...)
+		}),
 	), nil
 }
 
@@ -8,6 +8,7 @@ import (
 	"github.com/diamondburned/cchat"
 	"github.com/diamondburned/cchat-discord/internal/discord/channel/message/send"
 	"github.com/diamondburned/cchat-discord/internal/discord/channel/message/send/complete"
+	"github.com/diamondburned/cchat-discord/internal/discord/channel/shared"
 	"github.com/diamondburned/cchat-discord/internal/discord/state"
 	"github.com/diamondburned/cchat-discord/internal/discord/state/nonce"
 	"github.com/diamondburned/cchat/utils/empty"
@@ -89,14 +90,24 @@ func (s *Sender) Send(sendable cchat.SendableMessage) error {
 		s.adder.AddChannel(s.state, channel)
 	}
 
-	sendData := send.WrapMessage(s.state, sendable)
+	sendData := send.WrapMessage(shared.Channel{
+		ID:    channel.ID,
+		State: s.state,
+	}, sendable)
 	sendData.Content = strings.TrimPrefix(content, matches[0])
 
 	// Store the nonce.
 	s.sentMsgs.Store(sendData.Nonce)
 
-	_, err = s.state.SendMessageComplex(channel.ID, sendData)
-	return errors.Wrap(err, "failed to send message")
+	var original string
+	if noncer := sendable.AsNoncer(); noncer != nil {
+		original = noncer.Nonce()
+	}
+
+	// Send through the outbox, so the message is queued and shown as pending
+	// in the DM channel if it fails temporarily.
+	_, err = s.state.Outbox.Send(channel.ID, original, sendData)
+	return err
 }
 
 func (s *Sender) AsCompleter() cchat.Completer {
@@ -8,6 +8,7 @@ import (
 	"github.com/diamondburned/arikawa/v2/gateway"
 	"github.com/diamondburned/cchat"
 	"github.com/diamondburned/cchat-discord/internal/discord/channel"
+	"github.com/diamondburned/cchat-discord/internal/discord/private/friends"
 	"github.com/diamondburned/cchat-discord/internal/discord/private/hub"
 	"github.com/diamondburned/cchat-discord/internal/discord/state"
 	"github.com/diamondburned/cchat/text"
@@ -67,10 +68,15 @@ type Private struct {
 	empty.Server
 	state      *state.Instance
 	hub        *hub.Server
+	friends    *friends.Server
 	containers *containerSet
+	settings   cchat.Configurator
 }
 
-func New(s *state.Instance) (cchat.Server, error) {
+// New creates the private container. The status configurator and the
+// account's configuration are exposed on it, since cchat doesn't have
+// configurators on sessions.
+func New(s *state.Instance, status cchat.Configurator) (*Private, error) {
 	containers := newContainerSet()
 
 	hubServer, err := hub.New(s, containers)
@@ -78,10 +84,12 @@ func New(s *state.Instance) (cchat.Server, error) {
 		return nil, errors.Wrap(err, "failed to make hub server")
 	}
 
-	return Private{
+	return &Private{
 		state:      s,
 		hub:        hubServer,
+		friends:    friends.New(s, containers),
 		containers: containers,
+		settings:   configurators{status, s.Overrides.Account()},
 	}, nil
 }
 
@@ -96,6 +104,51 @@ func (priv Private) Name() text.Rich {
 
 func (priv Private) AsLister() cchat.Lister { return priv }
 
+// Close unbinds the handlers of the hub and friends servers, invalidating them
+// forever.
+func (priv Private) Close() {
+	priv.hub.Close()
+	priv.friends.Close()
+}
+
+// AsConfigurator returns the user's status and custom status along with the
+// account's configuration, which is saved with the session.
+func (priv Private) AsConfigurator() cchat.Configurator { return priv.settings }
+
+// configurators joins configurators with distinct keys into one.
+type configurators []cchat.Configurator
+
+func (cfgs configurators) Configuration() (map[string]string, error) {
+	var configMap = map[string]string{}
+
+	for _, cfg := range cfgs {
+		m, err := cfg.Configuration()
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range m {
+			configMap[k] = v
+		}
+	}
+
+	return configMap, nil
+}
+
+// SetConfiguration gives the map to all configurators, which only use their
+// own keys. The first error is returned.
+func (cfgs configurators) SetConfiguration(cfgMap map[string]string) error {
+	var firstErr error
+
+	for _, cfg := range cfgs {
+		if err := cfg.SetConfiguration(cfgMap); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
 type activeChannel struct {
 	*discord.Channel
 	*gateway.ReadState // used for sorting
@@ -138,8 +191,9 @@ func (priv Private) Servers(container cchat.ServersContainer) error {
 		return channels[i].LastMessageID() > channels[j].LastMessageID()
 	})
 
-	servers := make([]cchat.Server, len(channels)+1)
+	servers := make([]cchat.Server, len(channels)+2)
 	servers[0] = priv.hub
+	servers[1] = priv.friends
 
 	for i, ch := range channels {
 		c, err := channel.New(priv.state, *ch.Channel)
@@ -147,7 +201,7 @@ func (priv Private) Servers(container cchat.ServersContainer) error {
 			return errors.Wrap(err, "failed to create server for private channel")
 		}
 
-		servers[i+1] = c
+		servers[i+2] = c
 	}
 
 	container.SetServers(servers)
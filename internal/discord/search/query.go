@@ -0,0 +1,193 @@
+package search
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/bot/extras/arguments"
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/pkg/errors"
+)
+
+// Has is a type of content that messages can be filtered with.
+type Has string
+
+const (
+	HasLink    Has = "link"
+	HasEmbed   Has = "embed"
+	HasFile    Has = "file"
+	HasImage   Has = "image"
+	HasVideo   Has = "video"
+	HasSound   Has = "sound"
+	HasSticker Has = "sticker"
+)
+
+// parseHas parses the value of a has: filter. Attachment is accepted as an
+// alias for file.
+func parseHas(v string) (Has, error) {
+	switch has := Has(strings.ToLower(v)); has {
+	case HasLink, HasEmbed, HasFile, HasImage, HasVideo, HasSound, HasSticker:
+		return has, nil
+	case "attachment":
+		return HasFile, nil
+	default:
+		return "", errors.Errorf("unknown has: filter %q", v)
+	}
+}
+
+// Query is a message search query.
+type Query struct {
+	Content   string
+	AuthorID  discord.UserID
+	Mentions  discord.UserID
+	ChannelID discord.ChannelID
+	// ChannelName is the name given to the in: filter if it's not a channel
+	// ID or mention. It's resolved to ChannelID by Search.
+	ChannelName string
+	Has         []Has
+	// Before and After are exclusive bounds.
+	Before discord.MessageID
+	After  discord.MessageID
+	Offset int
+}
+
+// ParseQuery parses the given words into a query. Words that are not filters
+// are joined as the content. Supported filters are from: (or author:),
+// mentions:, in:, has:, before: and after:. Channels can be given as an ID, a
+// mention or a name. Dates can either be a message ID, a date in the format
+// 2006-01-02 or an RFC3339 timestamp.
+func ParseQuery(words []string) (Query, error) {
+	var q Query
+	var content []string
+
+	for _, word := range words {
+		i := strings.IndexByte(word, ':')
+		if i < 1 || i == len(word)-1 {
+			content = append(content, word)
+			continue
+		}
+
+		k, v := strings.ToLower(word[:i]), word[i+1:]
+
+		var err error
+
+		switch k {
+		case "from", "author":
+			q.AuthorID, err = parseUser(v)
+		case "mentions":
+			q.Mentions, err = parseUser(v)
+		case "in":
+			q.ChannelID, q.ChannelName = parseChannel(v)
+		case "has":
+			var has Has
+			has, err = parseHas(v)
+			q.Has = append(q.Has, has)
+		case "before":
+			q.Before, err = parseTime(v)
+		case "after":
+			q.After, err = parseTime(v)
+		default:
+			content = append(content, word)
+			continue
+		}
+
+		if err != nil {
+			return q, errors.Wrapf(err, "invalid %s: filter", k)
+		}
+	}
+
+	q.Content = strings.Join(content, " ")
+	return q, nil
+}
+
+// IsEmpty returns true if the query has no content nor filters.
+func (q Query) IsEmpty() bool {
+	return q.Content == "" &&
+		!q.AuthorID.IsValid() &&
+		!q.Mentions.IsValid() &&
+		!q.ChannelID.IsValid() &&
+		q.ChannelName == "" &&
+		!q.Before.IsValid() &&
+		!q.After.IsValid() &&
+		len(q.Has) == 0
+}
+
+// Values returns the query as URL values for the search endpoint.
+func (q Query) Values() url.Values {
+	var v = url.Values{}
+
+	if q.Content != "" {
+		v.Set("content", q.Content)
+	}
+	if q.AuthorID.IsValid() {
+		v.Set("author_id", q.AuthorID.String())
+	}
+	if q.Mentions.IsValid() {
+		v.Set("mentions", q.Mentions.String())
+	}
+	if q.ChannelID.IsValid() {
+		v.Set("channel_id", q.ChannelID.String())
+	}
+	if q.Before.IsValid() {
+		v.Set("max_id", q.Before.String())
+	}
+	if q.After.IsValid() {
+		v.Set("min_id", q.After.String())
+	}
+	if q.Offset > 0 {
+		v.Set("offset", strconv.Itoa(q.Offset))
+	}
+
+	for _, has := range q.Has {
+		v.Add("has", string(has))
+	}
+
+	return v
+}
+
+func parseUser(v string) (discord.UserID, error) {
+	if s, err := discord.ParseSnowflake(v); err == nil {
+		return discord.UserID(s), nil
+	}
+
+	var user arguments.UserMention
+	if err := user.Parse(v); err != nil {
+		return 0, err
+	}
+
+	return user.ID(), nil
+}
+
+// parseChannel parses the channel ID or mention. Anything else is returned as a
+// channel name.
+func parseChannel(v string) (discord.ChannelID, string) {
+	if s, err := discord.ParseSnowflake(v); err == nil {
+		return discord.ChannelID(s), ""
+	}
+
+	var ch arguments.ChannelMention
+	if err := ch.Parse(v); err == nil {
+		return ch.ID(), ""
+	}
+
+	return 0, strings.TrimPrefix(v, "#")
+}
+
+func parseTime(v string) (discord.MessageID, error) {
+	if s, err := discord.ParseSnowflake(v); err == nil {
+		return discord.MessageID(s), nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+		return discord.MessageID(discord.NewSnowflake(t)), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return 0, errors.New("expected a message ID or a date like 2006-01-02")
+	}
+
+	return discord.MessageID(discord.NewSnowflake(t)), nil
+}
@@ -0,0 +1,122 @@
+// Package search provides message searching through Discord's search endpoint
+// and a virtual server that renders the results.
+package search
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v2/api"
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/cchat-discord/internal/discord/state"
+	"github.com/pkg/errors"
+)
+
+// PageSize is the number of results Discord returns per request.
+const PageSize = 25
+
+// Results is the response of the search endpoint.
+type Results struct {
+	TotalResults int `json:"total_results"`
+	// Messages contains the results. Each result has the matched message
+	// surrounded by some context.
+	Messages [][]resultMessage `json:"messages"`
+
+	// DocumentsIndexed and RetryAfter are set if the guild is still being
+	// indexed.
+	DocumentsIndexed int `json:"documents_indexed,omitempty"`
+	RetryAfter       int `json:"retry_after,omitempty"`
+}
+
+type resultMessage struct {
+	discord.Message
+	Hit bool `json:"hit"`
+}
+
+// Hits returns the matched messages in the order that they're returned.
+func (r Results) Hits() []discord.Message {
+	var hits = make([]discord.Message, 0, len(r.Messages))
+
+	for _, result := range r.Messages {
+		for _, m := range result {
+			if m.Hit {
+				hits = append(hits, m.Message)
+				break
+			}
+		}
+	}
+
+	return hits
+}
+
+// ErrIndexing is returned if the guild is not yet indexed.
+type ErrIndexing struct {
+	RetryAfter int
+}
+
+func (err ErrIndexing) Error() string {
+	return "search index not yet available, try again in " +
+		strconv.Itoa(err.RetryAfter) + " seconds"
+}
+
+// Search searches for messages in the given guild, or in the given channel if
+// the guild ID is invalid. The hits have their guild ID set.
+func Search(s *state.Instance, guildID discord.GuildID, chID discord.ChannelID, q Query) (*Results, error) {
+	if q.IsEmpty() {
+		return nil, errors.New("empty search query")
+	}
+
+	if q.ChannelName != "" {
+		id, err := channelByName(s, guildID, q.ChannelName)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid in: filter")
+		}
+		q.ChannelID = id
+	}
+
+	var url string
+	if guildID.IsValid() {
+		url = api.EndpointGuilds + guildID.String() + "/messages/search"
+	} else {
+		url = api.EndpointChannels + chID.String() + "/messages/search"
+	}
+
+	var r Results
+
+	if err := s.RequestJSON(&r, "GET", url+"?"+q.Values().Encode()); err != nil {
+		return nil, errors.Wrap(err, "failed to search")
+	}
+
+	if r.TotalResults == 0 && r.RetryAfter > 0 {
+		return nil, ErrIndexing{r.RetryAfter}
+	}
+
+	for _, result := range r.Messages {
+		for i := range result {
+			result[i].GuildID = guildID
+		}
+	}
+
+	return &r, nil
+}
+
+// channelByName returns the ID of the guild's channel with the given name,
+// ignoring case.
+func channelByName(s *state.Instance, guildID discord.GuildID, name string) (discord.ChannelID, error) {
+	if !guildID.IsValid() {
+		return 0, errors.New("channel names can only be searched in guilds")
+	}
+
+	channels, err := s.Channels(guildID)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get channels")
+	}
+
+	for _, ch := range channels {
+		if ch.Type != discord.GuildCategory && strings.EqualFold(ch.Name, name) {
+			return ch.ID, nil
+		}
+	}
+
+	return 0, errors.Errorf("no channel named %q", name)
+}
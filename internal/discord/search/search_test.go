@@ -0,0 +1,159 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/cchat-discord/internal/discord/state/replay"
+)
+
+const timeout = 2 * time.Second
+
+func TestParseQuery(t *testing.T) {
+	q, err := ParseQuery([]string{
+		"hello", "from:<@2>", "in:<#20>", "has:attachment", "has:link",
+		"after:2021-01-01", "before:300", "world",
+	})
+	if err != nil {
+		t.Fatal("Failed to parse query:", err)
+	}
+
+	v := q.Values()
+
+	var expect = map[string][]string{
+		"content":    {"hello world"},
+		"author_id":  {"2"},
+		"channel_id": {"20"},
+		"has":        {"file", "link"},
+		"max_id":     {"300"},
+	}
+
+	for k, expect := range expect {
+		got := v[k]
+		if len(got) != len(expect) {
+			t.Errorf("%s: expected %v, got %v", k, expect, got)
+			continue
+		}
+		for i := range expect {
+			if got[i] != expect[i] {
+				t.Errorf("%s: expected %v, got %v", k, expect, got)
+			}
+		}
+	}
+
+	if !q.After.IsValid() || q.After.Time().Year() != 2021 {
+		t.Errorf("Unexpected after: %v", q.After.Time())
+	}
+
+	if _, err := ParseQuery([]string{"has:nothing"}); err == nil {
+		t.Error("Expected an error for an unknown has: filter")
+	}
+
+	q, err = ParseQuery([]string{"in:#General"})
+	if err != nil {
+		t.Fatal("Failed to parse query:", err)
+	}
+
+	if q.ChannelID.IsValid() || q.ChannelName != "General" {
+		t.Errorf("Unexpected channel %d %q", q.ChannelID, q.ChannelName)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	f, err := replay.Load("../state/replay/testdata/basic.json")
+	if err != nil {
+		t.Fatal("Failed to load fixture:", err)
+	}
+
+	f.REST = append(f.REST, replay.Response{
+		Method: "GET",
+		Path:   "/guilds/10/messages/search",
+		Body: json.RawMessage(`{
+			"total_results": 1,
+			"messages": [[
+				{"id": "199", "channel_id": "20", "content": "before", "timestamp": "2021-01-01T00:00:00+00:00", "author": {"id": "2", "username": "friend"}},
+				{"id": "200", "channel_id": "20", "content": "first", "timestamp": "2021-01-01T00:00:00+00:00", "author": {"id": "1", "username": "me"}, "hit": true}
+			]]
+		}`),
+	})
+
+	h, err := replay.New(f)
+	if err != nil {
+		t.Fatal("Failed to create harness:", err)
+	}
+	defer h.Close()
+
+	r, err := Search(h.Instance, 10, 20, Query{Content: "first", ChannelName: "General"})
+	if err != nil {
+		t.Fatal("Failed to search:", err)
+	}
+
+	hits := r.Hits()
+	if len(hits) != 1 || hits[0].ID != 200 || hits[0].GuildID != 10 {
+		t.Fatalf("Unexpected hits: %#v", hits)
+	}
+
+	requests := h.Requests()
+	if q := requests[len(requests)-1].Query; q.Get("content") != "first" || q.Get("channel_id") != "20" {
+		t.Errorf("Unexpected query %v", q)
+	}
+
+	result := NewResult(hits[0], h.Instance)
+	content := result.Content()
+
+	var hasReference bool
+	for _, seg := range content.Segments {
+		if ref := seg.AsMessageReferencer(); ref != nil {
+			hasReference = ref.MessageID() == discord.MessageID(200).String()
+		}
+	}
+
+	if !hasReference {
+		t.Errorf("Result has no reference to the message: %#v", content)
+	}
+
+	if _, err := Search(h.Instance, 10, 20, Query{ChannelName: "nowhere"}); err == nil {
+		t.Error("Expected an error for an unknown channel name")
+	}
+}
+
+func TestResultsEvent(t *testing.T) {
+	f, err := replay.Load("../state/replay/testdata/basic.json")
+	if err != nil {
+		t.Fatal("Failed to load fixture:", err)
+	}
+
+	h, err := replay.New(f)
+	if err != nil {
+		t.Fatal("Failed to create harness:", err)
+	}
+	defer h.Close()
+
+	srv := NewServer(h.Instance, 10)
+	defer srv.Close()
+
+	// Results of other guilds are ignored.
+	h.Call(&ResultsEvent{GuildID: 11, Hits: []discord.Message{{ID: 100, ChannelID: 40}}})
+	h.Call(&ResultsEvent{GuildID: 10, Hits: []discord.Message{{ID: 200, ChannelID: 20}}})
+
+	// The results are kept for containers that join afterwards.
+	ct := &replay.MessagesContainer{}
+
+	stop, err := srv.AsMessenger().JoinServer(context.Background(), ct)
+	if err != nil {
+		t.Fatal("Failed to join server:", err)
+	}
+	defer stop()
+
+	ok := ct.WaitFor(timeout, func() bool { return len(ct.Created) > 0 })
+	if !ok {
+		t.Fatal("Timed out waiting for the results")
+	}
+
+	if len(ct.Created) != 1 || ct.Created[0].ID() != "200" {
+		t.Fatalf("Unexpected results %v", ct.Created)
+	}
+}
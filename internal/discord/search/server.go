@@ -0,0 +1,196 @@
+package search
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-discord/internal/discord/channel/shared"
+	"github.com/diamondburned/cchat-discord/internal/discord/config"
+	"github.com/diamondburned/cchat-discord/internal/discord/message"
+	"github.com/diamondburned/cchat-discord/internal/discord/state"
+	"github.com/diamondburned/cchat-discord/internal/segments"
+	"github.com/diamondburned/cchat-discord/internal/segments/inline"
+	"github.com/diamondburned/cchat-discord/internal/segments/mention"
+	"github.com/diamondburned/cchat-discord/internal/segments/reference"
+	"github.com/diamondburned/cchat-discord/internal/segments/segutil"
+	"github.com/diamondburned/cchat/text"
+	"github.com/diamondburned/cchat/utils/empty"
+	"github.com/pkg/errors"
+)
+
+// Server is a virtual server that searches the guild for messages sent into it
+// and shows the results as messages.
+type Server struct {
+	empty.Server
+	guildID discord.GuildID
+	state   *state.Instance
+
+	msgs   *messenger
+	cancel func()
+}
+
+var _ cchat.Server = (*Server)(nil)
+
+// NewServer creates the search server of the guild. It shows the results of
+// ResultsEvents until it's closed.
+func NewServer(s *state.Instance, guildID discord.GuildID) *Server {
+	msgr := &messenger{
+		guildID:    guildID,
+		state:      s,
+		containers: map[cchat.MessagesContainer]struct{}{},
+	}
+
+	return &Server{
+		guildID: guildID,
+		state:   s,
+		msgs:    msgr,
+		cancel: s.AddHandler(func(ev *ResultsEvent) {
+			if ev.GuildID == guildID {
+				msgr.show(ev.Hits)
+			}
+		}),
+	}
+}
+
+// Close unbinds the handler, so results are no longer shown.
+func (srv *Server) Close() { srv.cancel() }
+
+func (srv *Server) ID() cchat.ID {
+	return "!!!search-" + srv.guildID.String() + "!!!"
+}
+
+func (srv *Server) Name() text.Rich { return text.Plain("🔍 Search") }
+
+func (srv *Server) AsMessenger() cchat.Messenger { return srv.msgs }
+
+// ResultsEvent is dispatched to show search results in the guild's search
+// server, such as the results of the search command.
+type ResultsEvent struct {
+	GuildID discord.GuildID
+	Hits    []discord.Message
+}
+
+type messenger struct {
+	empty.Messenger
+	empty.Sender
+	guildID discord.GuildID
+	state   *state.Instance
+
+	mut        sync.Mutex
+	containers map[cchat.MessagesContainer]struct{}
+	results    []discord.Message
+}
+
+func (msgr *messenger) JoinServer(ctx context.Context, ct cchat.MessagesContainer) (func(), error) {
+	msgr.mut.Lock()
+	defer msgr.mut.Unlock()
+
+	msgr.containers[ct] = struct{}{}
+
+	for _, m := range msgr.results {
+		ct.CreateMessage(NewResult(m, msgr.state))
+	}
+
+	return func() {
+		msgr.mut.Lock()
+		delete(msgr.containers, ct)
+		msgr.mut.Unlock()
+	}, nil
+}
+
+func (msgr *messenger) AsSender() cchat.Sender { return msgr }
+
+func (msgr *messenger) CanAttach() bool { return false }
+
+// Send searches using the message content as the query. The old results are
+// replaced with the new ones.
+func (msgr *messenger) Send(sendable cchat.SendableMessage) error {
+	q, err := ParseQuery(strings.Fields(sendable.Content()))
+	if err != nil {
+		return err
+	}
+
+	r, err := Search(msgr.state, msgr.guildID, 0, q)
+	if err != nil {
+		return err
+	}
+
+	hits := r.Hits()
+	if len(hits) == 0 {
+		return errors.New("no messages found")
+	}
+
+	msgr.show(hits)
+	return nil
+}
+
+// show replaces the old results in all containers with the given ones.
+func (msgr *messenger) show(hits []discord.Message) {
+	// Show the latest results at the bottom, like a channel.
+	hits = append([]discord.Message(nil), hits...)
+	sort.Slice(hits, func(i, j int) bool { return hits[i].ID < hits[j].ID })
+
+	msgr.mut.Lock()
+	defer msgr.mut.Unlock()
+
+	for ct := range msgr.containers {
+		for _, m := range msgr.results {
+			ct.DeleteMessage(NewResult(m, msgr.state))
+		}
+		for _, m := range hits {
+			ct.CreateMessage(NewResult(m, msgr.state))
+		}
+	}
+
+	msgr.results = hits
+}
+
+// Result is a search result. Its content has a header with the channel and a
+// reference to the original message.
+type Result struct {
+	message.Message
+	content text.Rich
+}
+
+var _ cchat.MessageCreate = (*Result)(nil)
+
+func NewResult(m discord.Message, s *state.Instance) Result {
+	var content text.Rich
+	WriteHeader(&content, m, s)
+	segments.ParseMessageRich(&content, &m, s.Cabinet, config.SyntaxHighlighting(s.Overrides))
+
+	return Result{
+		Message: message.NewBacklogMessage(m, s),
+		content: content,
+	}
+}
+
+func (r Result) Content() text.Rich { return r.content }
+
+// WriteHeader writes a dimmed line with the channel that the message was sent
+// in and a reference to the message.
+func WriteHeader(rich *text.Rich, m discord.Message, s *state.Instance) {
+	start := len(rich.Content)
+
+	ch, err := s.Cabinet.Channel(m.ChannelID)
+	if err != nil {
+		ch = &discord.Channel{ID: m.ChannelID, GuildID: m.GuildID}
+	}
+
+	chStart, chEnd := segutil.Write(rich, shared.ChannelName(*ch))
+	segutil.Add(rich, mention.Segment{
+		Start:   chStart,
+		End:     chEnd,
+		Channel: mention.NewChannel(*ch),
+	})
+
+	segutil.Write(rich, " · ")
+	reference.Write(rich, m.ID, "Jump to message")
+
+	segutil.Add(rich, inline.NewSegment(start, len(rich.Content), text.AttributeDimmed))
+	segutil.Write(rich, "\n")
+}
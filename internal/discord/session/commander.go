@@ -0,0 +1,41 @@
+package session
+
+import (
+	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-discord/internal/discord/channel/commands"
+)
+
+// AsCommander returns the session commands, which include the status commands.
+func (s *Session) AsCommander() cchat.Commander { return commander{s.commands()} }
+
+// commands returns the status commands and the session's own commands.
+func (s *Session) commands() commands.Commands {
+	return append(s.presence.Commands(), commands.Command{
+		Name: "mark-all-read",
+		Desc: "Mark all channels as read",
+		RunFunc: func(argv []string) ([]byte, error) {
+			if err := commands.AssertArgc(argv, 0); err != nil {
+				return nil, err
+			}
+
+			if err := s.state.MarkAllRead(); err != nil {
+				return nil, err
+			}
+			return []byte("Marked all as read."), nil
+		},
+	})
+}
+
+type commander struct {
+	commands commands.Commands
+}
+
+func (c commander) Run(words []string) ([]byte, error) {
+	return c.commands.Run(words)
+}
+
+func (c commander) AsCompleter() cchat.Completer { return c }
+
+func (c commander) Complete(words []string, i int64) []cchat.CompletionEntry {
+	return c.commands.Complete(words, i, nil)
+}
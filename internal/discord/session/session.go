@@ -2,14 +2,17 @@ package session
 
 import (
 	"context"
+	"sync"
 
 	"github.com/diamondburned/arikawa/v2/gateway"
 	"github.com/diamondburned/arikawa/v2/session"
 	"github.com/diamondburned/cchat"
 	"github.com/diamondburned/cchat-discord/internal/discord/folder"
 	"github.com/diamondburned/cchat-discord/internal/discord/guild"
+	"github.com/diamondburned/cchat-discord/internal/discord/presence"
 	"github.com/diamondburned/cchat-discord/internal/discord/private"
 	"github.com/diamondburned/cchat-discord/internal/discord/state"
+	"github.com/diamondburned/cchat-discord/internal/funcutil"
 	"github.com/diamondburned/cchat-discord/internal/urlutils"
 	"github.com/diamondburned/cchat/text"
 	"github.com/diamondburned/cchat/utils/empty"
@@ -21,19 +24,28 @@ var ErrMFA = session.ErrMFA
 
 type Session struct {
 	empty.Session
-	private cchat.Server
-	state   *state.Instance
+	private  *private.Private
+	state    *state.Instance
+	presence *presence.Manager
+
+	// stopServers removes the handlers bound by the last Servers call.
+	serversMutex sync.Mutex
+	stopServers  func()
 }
 
 func NewFromInstance(i *state.Instance) (cchat.Session, error) {
-	priv, err := private.New(i)
+	p := presence.New(i)
+
+	priv, err := private.New(i, p.Configurator())
 	if err != nil {
+		p.Close()
 		return nil, errors.Wrap(err, "failed to make main private server")
 	}
 
 	return &Session{
-		private: priv,
-		state:   i,
+		private:  priv,
+		state:    i,
+		presence: p,
 	}, nil
 }
 
@@ -71,21 +83,45 @@ func (s *Session) Icon(ctx context.Context, iconer cchat.IconContainer) (func(),
 }
 
 func (s *Session) Disconnect() error {
+	s.serversMutex.Lock()
+	if s.stopServers != nil {
+		s.stopServers()
+		s.stopServers = nil
+	}
+	s.serversMutex.Unlock()
+
+	s.presence.Close()
+	s.private.Close()
+
 	return s.state.Close()
 }
 
 func (s *Session) AsSessionSaver() cchat.SessionSaver { return s.state }
 
+// SetFocused sets whether the frontend is focused for this session.
+func (s *Session) SetFocused(focused bool) {
+	s.state.Unread.SetFocused(focused)
+}
+
 func (s *Session) Servers(container cchat.ServersContainer) error {
-	// Reset the entire container when the session is closed.
-	s.state.AddHandler(func(*session.Closed) {
-		container.SetServers(nil)
-	})
-
-	// Set the entire container again once reconnected.
-	s.state.AddHandler(func(*ningen.Connected) {
-		s.servers(container)
-	})
+	s.serversMutex.Lock()
+	defer s.serversMutex.Unlock()
+
+	// Only the latest container is kept updated.
+	if s.stopServers != nil {
+		s.stopServers()
+	}
+
+	s.stopServers = funcutil.JoinCancels(
+		// Reset the entire container when the session is closed.
+		s.state.AddHandler(func(*session.Closed) {
+			container.SetServers(nil)
+		}),
+		// Set the entire container again once reconnected.
+		s.state.AddHandler(func(*ningen.Connected) {
+			s.servers(container)
+		}),
+	)
 
 	return s.servers(container)
 }
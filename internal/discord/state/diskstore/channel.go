@@ -0,0 +1,358 @@
+package diskstore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/state/store/defaultstore"
+	"github.com/pkg/errors"
+)
+
+// maxRecordSize is the maximum size of a single log line.
+const maxRecordSize = 4 * 1024 * 1024
+
+const (
+	opSet    = "set"
+	opDelete = "del"
+	// opRun marks the oldest message of the contiguous run.
+	opRun = "run"
+)
+
+// record is a single line in the append log.
+type record struct {
+	Op      string            `json:"op"`
+	Message *discord.Message  `json:"m,omitempty"`
+	ID      discord.MessageID `json:"id,omitempty"`
+}
+
+type channel struct {
+	// io is held while the log file is read or written. It is acquired before
+	// mut.
+	io   sync.Mutex
+	mut  sync.Mutex
+	path string
+
+	// joined is the number of times the channel is currently joined. Only
+	// joined channels are loaded from and written to the disk.
+	joined int
+	loaded bool
+
+	// messages is sorted from latest to earliest.
+	messages []discord.Message
+	// oldest is the oldest message of the run of messages that is known to
+	// have no gaps, which ends at the latest message. Older messages were
+	// added out of order, such as replied messages, so they can't be paged
+	// through. It is zero if the run is unknown.
+	oldest discord.MessageID
+	// records is the number of records in the log file, used to know when to
+	// compact it.
+	records int
+
+	// pending is the encoded records that are yet to be appended to the log
+	// file. If rewrite is true, the log file is replaced with the encoded
+	// snapshot first.
+	pending  []byte
+	rewrite  bool
+	snapshot []byte
+
+	// stale is true if the messages might be outdated. Head is the latest
+	// message when the channel became stale.
+	stale bool
+	head  discord.MessageID
+}
+
+// load replays the log file and merges it with the messages that were received
+// while the channel wasn't loaded. Errors are logged, since the cache is not
+// essential.
+func (ch *channel) load(maxHistory int) {
+	var disk channel
+
+	corrupted, err := disk.replay(ch.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Println("[Discord] Failed to open message cache:", err)
+		}
+	}
+
+	ch.records = disk.records
+
+	switch {
+	case len(ch.messages) == 0:
+		ch.messages = disk.messages
+		ch.oldest = disk.oldest
+		ch.markStale()
+
+	// The run continues from the log if the messages in memory were received
+	// since the channel was last joined. The channel might already be stale
+	// since then, in which case the head is kept.
+	case disk.index(ch.messages[len(ch.messages)-1].ID) > -1:
+		ch.merge(disk.messages)
+		ch.oldest = disk.oldest
+
+		if !ch.stale {
+			ch.markStale()
+		}
+
+		corrupted = true
+
+	// Otherwise, there could be a gap between the log and the messages in
+	// memory, so the run is unknown until the channel is synchronized.
+	default:
+		ch.merge(disk.messages)
+		ch.oldest = 0
+		ch.markStale()
+
+		corrupted = true
+	}
+
+	ch.trim(maxHistory)
+
+	if corrupted {
+		if err := ch.queueSnapshot(); err != nil {
+			log.Println("[Discord] Failed to rewrite message cache:", err)
+		}
+	}
+}
+
+// merge adds the messages that aren't in the channel yet.
+func (ch *channel) merge(msgs []discord.Message) {
+	for _, m := range msgs {
+		if i := ch.index(m.ID); i > -1 {
+			continue
+		}
+		ch.insert(m)
+	}
+}
+
+// replay replays the log file at the given path into the channel. It returns
+// true if the log should be rewritten.
+func (ch *channel) replay(path string) (corrupted bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, maxRecordSize)
+
+	var hasRun bool
+
+	for scanner.Scan() {
+		var r record
+
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			// A partially written record is most likely the last one, so we
+			// keep what we have.
+			corrupted = true
+			break
+		}
+
+		ch.records++
+
+		switch r.Op {
+		case opSet:
+			if r.Message == nil {
+				continue
+			}
+
+			if i := ch.index(r.Message.ID); i > -1 {
+				defaultstore.DiffMessage(*r.Message, &ch.messages[i])
+			} else {
+				ch.insert(*r.Message)
+			}
+
+		case opDelete:
+			if i := ch.index(r.ID); i > -1 {
+				ch.messages = append(ch.messages[:i], ch.messages[i+1:]...)
+			}
+
+		case opRun:
+			ch.oldest = r.ID
+			hasRun = true
+		}
+	}
+
+	// Logs written by older versions don't have the run, and they were
+	// assumed to be contiguous.
+	if !hasRun && len(ch.messages) > 0 {
+		ch.oldest = ch.messages[len(ch.messages)-1].ID
+	}
+
+	return corrupted || scanner.Err() != nil, nil
+}
+
+func (ch *channel) markStale() {
+	ch.stale = true
+	ch.head = 0
+
+	if len(ch.messages) > 0 {
+		ch.head = ch.messages[0].ID
+	}
+}
+
+// index returns the index of the message with the given ID or -1.
+func (ch *channel) index(id discord.MessageID) int {
+	for i, m := range ch.messages {
+		if m.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// insert inserts the message while keeping the list sorted.
+func (ch *channel) insert(m discord.Message) {
+	i := 0
+	for i < len(ch.messages) && ch.messages[i].ID > m.ID {
+		i++
+	}
+
+	ch.messages = append(ch.messages, discord.Message{})
+	copy(ch.messages[i+1:], ch.messages[i:])
+	ch.messages[i] = m
+}
+
+// trim removes the oldest messages beyond the given maximum.
+func (ch *channel) trim(max int) {
+	if len(ch.messages) <= max {
+		return
+	}
+
+	// Move the start of the run if it's trimmed off.
+	if ch.oldest.IsValid() && ch.messages[max].ID >= ch.oldest {
+		ch.oldest = ch.messages[max-1].ID
+	}
+
+	for i := max; i < len(ch.messages); i++ {
+		ch.messages[i] = discord.Message{} // avoid leaking memory
+	}
+
+	ch.messages = ch.messages[:max]
+}
+
+// queue queues the record to be appended into the log file. The log is
+// compacted instead if it has too many records.
+func (ch *channel) queue(r record, maxHistory int) error {
+	if ch.records >= maxHistory*2 {
+		return ch.queueSnapshot()
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal record")
+	}
+
+	ch.pending = append(append(ch.pending, b...), '\n')
+	ch.records++
+	return nil
+}
+
+// queueSnapshot queues the log file to be rewritten with only the current
+// messages.
+func (ch *channel) queueSnapshot() error {
+	var buf bytes.Buffer
+	var enc = json.NewEncoder(&buf)
+
+	if err := enc.Encode(record{Op: opRun, ID: ch.oldest}); err != nil {
+		return errors.Wrap(err, "failed to marshal record")
+	}
+
+	// Write from earliest to latest, so the messages are replayed in order.
+	for i := len(ch.messages) - 1; i >= 0; i-- {
+		if err := enc.Encode(record{Op: opSet, Message: &ch.messages[i]}); err != nil {
+			return errors.Wrap(err, "failed to marshal record")
+		}
+	}
+
+	ch.rewrite = true
+	ch.snapshot = buf.Bytes()
+	ch.pending = nil
+	ch.records = len(ch.messages)
+	return nil
+}
+
+// dirty returns true if the channel has changes that aren't written yet.
+func (ch *channel) dirty() bool {
+	return ch.rewrite || len(ch.pending) > 0
+}
+
+// take returns the queued changes and clears them.
+func (ch *channel) take() (rewrite bool, snapshot, pending []byte) {
+	rewrite, snapshot, pending = ch.rewrite, ch.snapshot, ch.pending
+	ch.rewrite, ch.snapshot, ch.pending = false, nil, nil
+	return
+}
+
+// flush writes the queued changes into the log file. The channel must not be
+// locked.
+func (ch *channel) flush() error {
+	ch.io.Lock()
+	defer ch.io.Unlock()
+
+	ch.mut.Lock()
+	rewrite, snapshot, pending := ch.take()
+	ch.mut.Unlock()
+
+	return write(ch.path, rewrite, snapshot, pending)
+}
+
+// write replaces the log file with the snapshot if rewrite is true, then
+// appends the pending records.
+func write(path string, rewrite bool, snapshot, pending []byte) error {
+	if rewrite {
+		if err := replace(path, snapshot); err != nil {
+			return err
+		}
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to open message cache")
+	}
+
+	if _, err := f.Write(pending); err != nil {
+		f.Close()
+		return errors.Wrap(err, "failed to write message cache")
+	}
+
+	return errors.Wrap(f.Close(), "failed to close message cache")
+}
+
+// replace atomically replaces the log file with the given content.
+func replace(path string, content []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary file")
+	}
+
+	// Clean up the temporary file if anything goes wrong. This is a no-op
+	// after the rename.
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return errors.Wrap(err, "failed to write message cache")
+	}
+
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "failed to close message cache")
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return errors.Wrap(err, "failed to replace message cache")
+	}
+
+	return nil
+}
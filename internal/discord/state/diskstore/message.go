@@ -0,0 +1,463 @@
+// Package diskstore provides a message store that persists messages on disk, so
+// channels can be rendered from the cache across restarts.
+package diskstore
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/state/store"
+	"github.com/diamondburned/arikawa/v2/state/store/defaultstore"
+	"github.com/pkg/errors"
+)
+
+// HistoryMultiplier is the number of pages of messages kept per joined channel
+// on top of the maximum number of messages.
+const HistoryMultiplier = 10
+
+// MaxChannels is the maximum number of channel logs kept on disk. The logs that
+// were least recently written are removed first.
+const MaxChannels = 100
+
+// flushInterval is how often changes to joined channels are written to the
+// disk.
+const flushInterval = 2 * time.Second
+
+// Message is a message store that keeps an append log on disk for each joined
+// channel. Other channels only have their latest messages kept in memory.
+// Messages loaded from the disk are stale until they're synchronized with Sync,
+// as messages may have been sent while the client was offline.
+type Message struct {
+	dir        string
+	maxMsgs    int
+	maxHistory int
+
+	mut      sync.Mutex
+	channels map[discord.ChannelID]*channel
+	dirty    map[*channel]struct{}
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+var _ store.MessageStore = (*Message)(nil)
+
+// NewMessage creates a new message store that keeps its logs in the given
+// directory. The directory is created if it doesn't exist. Changes are written
+// in the background until the store is closed.
+func NewMessage(dir string, maxMsgs int) (*Message, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "failed to make cache directory")
+	}
+
+	if err := prune(dir, MaxChannels); err != nil {
+		log.Println("[Discord] Failed to clean up message cache:", err)
+	}
+
+	s := &Message{
+		dir:        dir,
+		maxMsgs:    maxMsgs,
+		maxHistory: maxMsgs * HistoryMultiplier,
+		channels:   map[discord.ChannelID]*channel{},
+		dirty:      map[*channel]struct{}{},
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	go s.flushLoop()
+
+	return s, nil
+}
+
+// prune removes the least recently written logs in the directory beyond the
+// given maximum.
+func prune(dir string, max int) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return errors.Wrap(err, "failed to read cache directory")
+	}
+
+	var logs []os.FileInfo
+	for _, f := range files {
+		if f.Mode().IsRegular() && filepath.Ext(f.Name()) == ".log" {
+			logs = append(logs, f)
+		}
+	}
+
+	if len(logs) <= max {
+		return nil
+	}
+
+	sort.Slice(logs, func(i, j int) bool {
+		return logs[i].ModTime().After(logs[j].ModTime())
+	})
+
+	for _, f := range logs[max:] {
+		if err := os.Remove(filepath.Join(dir, f.Name())); err != nil {
+			return errors.Wrap(err, "failed to remove message cache")
+		}
+	}
+
+	return nil
+}
+
+// Close writes the remaining changes to the disk and stops writing. Changes
+// made afterwards are only kept in memory.
+func (s *Message) Close() error {
+	s.once.Do(func() { close(s.stop) })
+	<-s.done
+	return nil
+}
+
+func (s *Message) flushLoop() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			s.flush()
+			close(s.done)
+			return
+		case <-ticker.C:
+			s.flush()
+		}
+	}
+}
+
+// flush writes the changes of all dirty channels.
+func (s *Message) flush() {
+	s.mut.Lock()
+	dirty := s.dirty
+	s.dirty = map[*channel]struct{}{}
+	s.mut.Unlock()
+
+	for ch := range dirty {
+		if err := ch.flush(); err != nil {
+			log.Println("[Discord] Failed to write message cache:", err)
+		}
+	}
+}
+
+// markDirty marks the channel to be written on the next flush.
+func (s *Message) markDirty(ch *channel) {
+	s.mut.Lock()
+	s.dirty[ch] = struct{}{}
+	s.mut.Unlock()
+}
+
+// get returns the channel with the given ID.
+func (s *Message) get(id discord.ChannelID) *channel {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	ch, ok := s.channels[id]
+	if !ok {
+		ch = &channel{
+			path: filepath.Join(s.dir, id.String()+".log"),
+		}
+		s.channels[id] = ch
+	}
+
+	return ch
+}
+
+// channel returns the channel with the given ID. The returned channel is
+// locked.
+func (s *Message) channel(id discord.ChannelID) *channel {
+	ch := s.get(id)
+	ch.mut.Lock()
+	return ch
+}
+
+// limit returns the maximum number of messages kept in memory for the
+// channel.
+func (s *Message) limit(ch *channel) int {
+	if ch.loaded {
+		return s.maxHistory
+	}
+	return s.maxMsgs
+}
+
+// Join loads the channel's messages from the disk and keeps its log up to date
+// until the returned function is called. The channel is stale once loaded.
+func (s *Message) Join(chID discord.ChannelID) func() {
+	ch := s.get(chID)
+
+	ch.io.Lock()
+	ch.mut.Lock()
+
+	ch.joined++
+
+	if !ch.loaded {
+		ch.loaded = true
+
+		// Write what's left from the last time that the channel was joined.
+		rewrite, snapshot, pending := ch.take()
+		if err := write(ch.path, rewrite, snapshot, pending); err != nil {
+			log.Println("[Discord] Failed to write message cache:", err)
+		}
+
+		ch.load(s.maxHistory)
+	}
+
+	ch.mut.Unlock()
+	ch.io.Unlock()
+
+	if ch.dirty() {
+		s.markDirty(ch)
+	}
+
+	var once sync.Once
+	return func() { once.Do(func() { s.leave(ch) }) }
+}
+
+// leave unloads the channel once it's no longer joined. Its queued changes are
+// still written.
+func (s *Message) leave(ch *channel) {
+	ch.mut.Lock()
+	defer ch.mut.Unlock()
+
+	if ch.joined--; ch.joined > 0 {
+		return
+	}
+
+	ch.loaded = false
+	ch.trim(s.maxMsgs)
+}
+
+// Reset marks all messages as stale instead of clearing them, since the state
+// resets the store on every Ready event.
+func (s *Message) Reset() error {
+	s.mut.Lock()
+	var channels = make([]*channel, 0, len(s.channels))
+	for _, ch := range s.channels {
+		channels = append(channels, ch)
+	}
+	s.mut.Unlock()
+
+	for _, ch := range channels {
+		ch.mut.Lock()
+		ch.markStale()
+		ch.mut.Unlock()
+	}
+
+	return nil
+}
+
+func (s *Message) MaxMessages() int {
+	return s.maxMsgs
+}
+
+func (s *Message) Message(chID discord.ChannelID, mID discord.MessageID) (*discord.Message, error) {
+	ch := s.channel(chID)
+	defer ch.mut.Unlock()
+
+	if i := ch.index(mID); i > -1 {
+		m := ch.messages[i]
+		return &m, nil
+	}
+
+	return nil, store.ErrNotFound
+}
+
+// Messages returns the latest messages in the channel, up to the maximum
+// number of messages. Messages that were added out of order are left out. The
+// messages may be stale.
+func (s *Message) Messages(chID discord.ChannelID) ([]discord.Message, error) {
+	ch := s.channel(chID)
+	defer ch.mut.Unlock()
+
+	var msgs []discord.Message
+
+	for _, m := range ch.messages {
+		if m.ID < ch.oldest || len(msgs) == s.maxMsgs {
+			break
+		}
+		msgs = append(msgs, m)
+	}
+
+	if len(msgs) == 0 {
+		return nil, store.ErrNotFound
+	}
+
+	return msgs, nil
+}
+
+func (s *Message) MessageSet(m discord.Message) error {
+	ch := s.channel(m.ChannelID)
+	defer ch.mut.Unlock()
+
+	if i := ch.index(m.ID); i > -1 {
+		defaultstore.DiffMessage(m, &ch.messages[i])
+		m = ch.messages[i]
+	} else {
+		// The first message of an empty channel starts the run. Later
+		// messages either continue it or are older than it.
+		if len(ch.messages) == 0 && !ch.oldest.IsValid() {
+			ch.oldest = m.ID
+		}
+
+		ch.insert(m)
+		ch.trim(s.limit(ch))
+	}
+
+	return s.queue(ch, record{Op: opSet, Message: &m})
+}
+
+func (s *Message) MessageRemove(chID discord.ChannelID, mID discord.MessageID) error {
+	ch := s.channel(chID)
+	defer ch.mut.Unlock()
+
+	i := ch.index(mID)
+	if i == -1 {
+		return nil
+	}
+
+	ch.messages = append(ch.messages[:i], ch.messages[i+1:]...)
+
+	return s.queue(ch, record{Op: opDelete, ID: mID})
+}
+
+// Stale returns true if the channel was not synchronized since it was loaded or
+// since the last Ready event. Its cached messages can't be paged through until
+// then.
+func (s *Message) Stale(chID discord.ChannelID) bool {
+	ch := s.channel(chID)
+	defer ch.mut.Unlock()
+
+	return ch.stale
+}
+
+// Sync replaces the latest messages in the channel with the given fresh
+// messages from the API. The run of messages continues into the older cached
+// messages only if the fresh ones overlap with it, otherwise the older messages
+// are kept as if they were added out of order. The channel will no longer be
+// stale.
+func (s *Message) Sync(chID discord.ChannelID, fresh []discord.Message) error {
+	ch := s.channel(chID)
+	defer ch.mut.Unlock()
+
+	fresh = sortedCopy(fresh)
+
+	var older []discord.Message
+	var oldest discord.MessageID
+
+	if len(fresh) > 0 {
+		oldest = fresh[len(fresh)-1].ID
+
+		for _, m := range ch.messages {
+			if m.ID < oldest {
+				older = append(older, m)
+			}
+		}
+
+		// The run was contiguous up to the head when the channel became stale.
+		if ch.oldest.IsValid() && ch.oldest <= oldest && oldest <= ch.head {
+			oldest = ch.oldest
+		}
+	}
+
+	ch.messages = append(fresh, older...)
+	ch.oldest = oldest
+	ch.stale = false
+	ch.trim(s.limit(ch))
+
+	return s.snapshot(ch)
+}
+
+// Before returns up to limit cached messages older than the given message ID,
+// from latest to earliest. Only messages in the contiguous run are returned, so
+// fewer messages than the limit means that the rest should be fetched. Nothing
+// is returned if the channel is stale.
+func (s *Message) Before(chID discord.ChannelID, before discord.MessageID, limit int) []discord.Message {
+	ch := s.channel(chID)
+	defer ch.mut.Unlock()
+
+	if ch.stale {
+		return nil
+	}
+
+	var msgs []discord.Message
+
+	for _, m := range ch.messages {
+		if m.ID >= before {
+			continue
+		}
+
+		if m.ID < ch.oldest || len(msgs) == limit {
+			break
+		}
+
+		msgs = append(msgs, m)
+	}
+
+	return msgs
+}
+
+// Backfill adds the given messages fetched from before the given message ID.
+// The run of messages is extended if that message is within it, meaning that
+// the history stays contiguous. Otherwise, nothing is added.
+func (s *Message) Backfill(chID discord.ChannelID, before discord.MessageID, older []discord.Message) error {
+	ch := s.channel(chID)
+	defer ch.mut.Unlock()
+
+	if ch.stale || !ch.oldest.IsValid() || before < ch.oldest {
+		return nil
+	}
+
+	for _, m := range older {
+		if m.ID >= before {
+			continue
+		}
+
+		if i := ch.index(m.ID); i > -1 {
+			defaultstore.DiffMessage(m, &ch.messages[i])
+		} else {
+			ch.insert(m)
+		}
+
+		if m.ID < ch.oldest {
+			ch.oldest = m.ID
+		}
+	}
+
+	ch.trim(s.limit(ch))
+
+	return s.snapshot(ch)
+}
+
+// queue queues the record into the log of the locked channel if it's loaded.
+func (s *Message) queue(ch *channel, r record) error {
+	if !ch.loaded {
+		return nil
+	}
+
+	s.markDirty(ch)
+	return ch.queue(r, s.maxHistory)
+}
+
+// snapshot queues the log of the locked channel to be rewritten if it's
+// loaded.
+func (s *Message) snapshot(ch *channel) error {
+	if !ch.loaded {
+		return nil
+	}
+
+	s.markDirty(ch)
+	return ch.queueSnapshot()
+}
+
+// sortedCopy returns a copy of the messages sorted from latest to earliest.
+func sortedCopy(msgs []discord.Message) []discord.Message {
+	msgs = append([]discord.Message(nil), msgs...)
+	sort.Slice(msgs, func(i, j int) bool { return msgs[i].ID > msgs[j].ID })
+	return msgs
+}
@@ -0,0 +1,261 @@
+package diskstore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+)
+
+func newTestStore(t *testing.T, dir string) *Message {
+	t.Helper()
+
+	s, err := NewMessage(dir, 2)
+	if err != nil {
+		t.Fatal("Failed to create store:", err)
+	}
+
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "diskstore-")
+	if err != nil {
+		t.Fatal("Failed to create temporary directory:", err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func msg(id discord.MessageID, content string) discord.Message {
+	return discord.Message{ID: id, ChannelID: 1, Content: content}
+}
+
+func ids(msgs []discord.Message) []discord.MessageID {
+	var ids = make([]discord.MessageID, len(msgs))
+	for i, m := range msgs {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+func assertIDs(t *testing.T, got []discord.Message, expect ...discord.MessageID) {
+	t.Helper()
+
+	gotIDs := ids(got)
+
+	if len(gotIDs) != len(expect) {
+		t.Fatalf("Expected messages %v, got %v", expect, gotIDs)
+	}
+
+	for i := range expect {
+		if gotIDs[i] != expect[i] {
+			t.Fatalf("Expected messages %v, got %v", expect, gotIDs)
+		}
+	}
+}
+
+func TestPersist(t *testing.T) {
+	dir := tempDir(t)
+
+	s := newTestStore(t, dir)
+	s.Join(1)
+	s.MessageSet(msg(10, "a"))
+	s.MessageSet(msg(12, "c"))
+	s.MessageSet(msg(11, "b"))
+	s.MessageSet(msg(12, "c edited"))
+	s.MessageRemove(1, 10)
+	s.Close()
+
+	s = newTestStore(t, dir)
+	s.Join(1)
+
+	if !s.Stale(1) {
+		t.Fatal("Loaded channel is not stale")
+	}
+
+	m, err := s.Messages(1)
+	if err != nil {
+		t.Fatal("Failed to get messages:", err)
+	}
+
+	assertIDs(t, m, 12, 11)
+
+	if m[0].Content != "c edited" {
+		t.Errorf("Unexpected content %q", m[0].Content)
+	}
+}
+
+func TestSync(t *testing.T) {
+	s := newTestStore(t, tempDir(t))
+	s.Join(1)
+	s.MessageSet(msg(10, ""))
+	s.MessageSet(msg(11, ""))
+	s.MessageSet(msg(12, ""))
+	s.Reset()
+
+	// Overlapping with the cache, so older messages are kept.
+	if err := s.Sync(1, []discord.Message{msg(13, ""), msg(12, "")}); err != nil {
+		t.Fatal("Failed to sync:", err)
+	}
+
+	if s.Stale(1) {
+		t.Fatal("Channel is still stale after syncing")
+	}
+
+	assertIDs(t, s.Before(1, 12, 5), 11, 10)
+
+	s.Reset()
+
+	// Not overlapping, so the older messages may have a gap and are dropped.
+	if err := s.Sync(1, []discord.Message{msg(21, ""), msg(20, "")}); err != nil {
+		t.Fatal("Failed to sync:", err)
+	}
+
+	assertIDs(t, s.Before(1, 22, 5), 21, 20)
+}
+
+func TestBackfill(t *testing.T) {
+	s := newTestStore(t, tempDir(t))
+	s.Join(1)
+
+	if err := s.Sync(1, []discord.Message{msg(20, ""), msg(21, "")}); err != nil {
+		t.Fatal("Failed to sync:", err)
+	}
+
+	// Not contiguous, so nothing should be added.
+	s.Backfill(1, 15, []discord.Message{msg(5, "")})
+	assertIDs(t, s.Before(1, 20, 5))
+
+	s.Backfill(1, 20, []discord.Message{msg(18, ""), msg(19, "")})
+	assertIDs(t, s.Before(1, 20, 5), 19, 18)
+}
+
+func TestOutOfOrder(t *testing.T) {
+	s := newTestStore(t, tempDir(t))
+	s.Join(1)
+
+	if err := s.Sync(1, []discord.Message{msg(20, ""), msg(21, "")}); err != nil {
+		t.Fatal("Failed to sync:", err)
+	}
+
+	// A replied message that was fetched on its own.
+	s.MessageSet(msg(5, ""))
+
+	// It isn't part of the run, so it can't be paged through.
+	assertIDs(t, s.Before(1, 20, 5))
+
+	m, _ := s.Messages(1)
+	assertIDs(t, m, 21, 20)
+
+	// The run is still extended by pages fetched before it.
+	s.Backfill(1, 20, []discord.Message{msg(18, ""), msg(19, "")})
+	assertIDs(t, s.Before(1, 20, 5), 19, 18)
+
+	s.Backfill(1, 18, []discord.Message{msg(4, ""), msg(5, ""), msg(6, "")})
+	assertIDs(t, s.Before(1, 18, 5), 6, 5, 4)
+}
+
+func TestCorruptedLog(t *testing.T) {
+	dir := tempDir(t)
+
+	s := newTestStore(t, dir)
+	s.Join(1)
+	s.MessageSet(msg(10, "a"))
+	s.Close()
+
+	f, err := os.OpenFile(filepath.Join(dir, "1.log"), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal("Failed to open log:", err)
+	}
+	f.WriteString(`{"op":"set","m":{"id":"11"`)
+	f.Close()
+
+	s = newTestStore(t, dir)
+	s.Join(1)
+
+	m, err := s.Messages(1)
+	if err != nil {
+		t.Fatal("Failed to get messages:", err)
+	}
+
+	assertIDs(t, m, 10)
+}
+
+func TestNotJoined(t *testing.T) {
+	dir := tempDir(t)
+
+	s := newTestStore(t, dir)
+	s.MessageSet(msg(10, ""))
+	s.MessageSet(msg(11, ""))
+	s.MessageSet(msg(12, ""))
+	s.Close()
+
+	if _, err := os.Stat(filepath.Join(dir, "1.log")); !os.IsNotExist(err) {
+		t.Fatal("Log was written for a channel that was never joined:", err)
+	}
+
+	// Only a page of messages is kept in memory.
+	m, _ := s.Messages(1)
+	assertIDs(t, m, 12, 11)
+	assertIDs(t, s.Before(1, 13, 5), 12, 11)
+}
+
+func TestLeave(t *testing.T) {
+	dir := tempDir(t)
+
+	s := newTestStore(t, dir)
+	leave := s.Join(1)
+	s.MessageSet(msg(10, ""))
+	s.MessageSet(msg(11, ""))
+	s.MessageSet(msg(12, ""))
+	leave()
+
+	// Messages received after leaving are merged with the log once the
+	// channel is joined again.
+	s.MessageSet(msg(13, ""))
+	s.Join(1)
+	s.Close()
+
+	s = newTestStore(t, dir)
+	s.Join(1)
+
+	for _, id := range []discord.MessageID{10, 11, 12, 13} {
+		if _, err := s.Message(1, id); err != nil {
+			t.Errorf("Message %d is missing from the log", id)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := tempDir(t)
+
+	for i := 0; i < MaxChannels+2; i++ {
+		path := filepath.Join(dir, strconv.Itoa(i)+".log")
+
+		if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal("Failed to write log:", err)
+		}
+
+		// Make the lower IDs older.
+		mtime := time.Now().Add(time.Duration(i-MaxChannels) * time.Minute)
+		os.Chtimes(path, mtime, mtime)
+	}
+
+	newTestStore(t, dir)
+
+	for i := 0; i < MaxChannels+2; i++ {
+		_, err := os.Stat(filepath.Join(dir, strconv.Itoa(i)+".log"))
+		if removed := os.IsNotExist(err); removed != (i < 2) {
+			t.Errorf("Log %d: expected removed to be %t", i, i < 2)
+		}
+	}
+}
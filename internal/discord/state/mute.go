@@ -0,0 +1,164 @@
+package state
+
+import (
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/api"
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/arikawa/v2/utils/httputil"
+	"github.com/pkg/errors"
+)
+
+// NotificationLevels maps the names of the notification levels to their
+// values. The default level only applies to channels, which then follow their
+// guild's level.
+var NotificationLevels = map[string]gateway.UserNotification{
+	"all":      gateway.AllNotifications,
+	"mentions": gateway.OnlyMentions,
+	"nothing":  gateway.NoNotifications,
+	"default":  gateway.GuildDefaults,
+}
+
+// ParseNotifications parses the name of a notification level.
+func ParseNotifications(name string) (gateway.UserNotification, error) {
+	n, ok := NotificationLevels[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return 0, errors.Errorf("unknown notification level %q", name)
+	}
+	return n, nil
+}
+
+// ParseMuteDuration parses the optional duration argument of the mute commands.
+// A zero duration mutes until unmuted.
+func ParseMuteDuration(argv []string) (time.Duration, error) {
+	switch len(argv) {
+	case 0:
+		return 0, nil
+	case 1:
+		// Parsed below.
+	default:
+		return 0, errors.New("too many arguments")
+	}
+
+	d, err := time.ParseDuration(argv[0])
+	if err != nil {
+		return 0, errors.Wrap(err, "invalid duration")
+	}
+
+	if d <= 0 {
+		return 0, errors.New("duration must be positive")
+	}
+
+	return d, nil
+}
+
+// MuteGuild mutes the guild for the given duration, or until it is unmuted if
+// the duration is 0.
+func (s *Instance) MuteGuild(guildID discord.GuildID, d time.Duration) error {
+	return s.updateGuildSettings(guildID, muteSettings(true, d))
+}
+
+// UnmuteGuild unmutes the guild.
+func (s *Instance) UnmuteGuild(guildID discord.GuildID) error {
+	return s.updateGuildSettings(guildID, muteSettings(false, 0))
+}
+
+// SetGuildNotifications sets the notification level of the guild.
+func (s *Instance) SetGuildNotifications(guildID discord.GuildID, n gateway.UserNotification) error {
+	if n == gateway.GuildDefaults {
+		return errors.New("guilds have no default notification level")
+	}
+
+	return s.updateGuildSettings(guildID, map[string]interface{}{
+		"message_notifications": n,
+	})
+}
+
+// SuppressEveryone sets whether @everyone and @here mentions in the guild are
+// suppressed.
+func (s *Instance) SuppressEveryone(guildID discord.GuildID, suppress bool) error {
+	return s.updateGuildSettings(guildID, map[string]interface{}{
+		"suppress_everyone": suppress,
+	})
+}
+
+// MuteChannel mutes the channel or category for the given duration, or until
+// it is unmuted if the duration is 0.
+func (s *Instance) MuteChannel(chID discord.ChannelID, d time.Duration) error {
+	return s.updateChannelOverride(chID, muteSettings(true, d))
+}
+
+// UnmuteChannel unmutes the channel or category.
+func (s *Instance) UnmuteChannel(chID discord.ChannelID) error {
+	return s.updateChannelOverride(chID, muteSettings(false, 0))
+}
+
+// SetChannelNotifications sets the notification level of the channel or
+// category.
+func (s *Instance) SetChannelNotifications(chID discord.ChannelID, n gateway.UserNotification) error {
+	return s.updateChannelOverride(chID, map[string]interface{}{
+		"message_notifications": n,
+	})
+}
+
+func (s *Instance) updateChannelOverride(chID discord.ChannelID, override interface{}) error {
+	ch, err := s.Channel(chID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get channel")
+	}
+
+	return s.updateGuildSettings(ch.GuildID, map[string]interface{}{
+		"channel_overrides": map[string]interface{}{
+			chID.String(): override,
+		},
+	})
+}
+
+// updateGuildSettings patches the user's settings of the guild. Private
+// channels belong to the invalid guild ID.
+func (s *Instance) updateGuildSettings(guildID discord.GuildID, settings interface{}) error {
+	id := "@me"
+	if guildID.IsValid() {
+		id = guildID.String()
+	}
+
+	var updated gateway.UserGuildSetting
+
+	err := s.RequestJSON(
+		&updated, "PATCH",
+		api.EndpointMe+"/guilds/"+id+"/settings",
+		httputil.WithJSONBody(settings),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update guild settings")
+	}
+
+	// Dispatch the new settings as if the gateway had sent them, so that the
+	// muted state is updated without waiting for the event.
+	s.Session.Handler.Call(&gateway.UserGuildSettingsUpdateEvent{
+		UserGuildSetting: updated,
+	})
+
+	return nil
+}
+
+// muteSettings returns the settings that mute or unmute a guild or channel.
+func muteSettings(muted bool, d time.Duration) map[string]interface{} {
+	settings := map[string]interface{}{"muted": muted}
+
+	if muted {
+		config := gateway.UserMuteConfig{SelectedTimeWindow: -1}
+		if d > 0 {
+			config.SelectedTimeWindow = int(d / time.Second)
+			config.EndTime = discord.NewTimestamp(time.Now().Add(d))
+		}
+
+		settings["mute_config"] = config
+	} else {
+		settings["mute_config"] = nil
+	}
+
+	return settings
+}
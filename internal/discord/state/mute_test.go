@@ -0,0 +1,30 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMuteDuration(t *testing.T) {
+	var tests = []struct {
+		argv   []string
+		expect time.Duration
+		err    bool
+	}{
+		{nil, 0, false},
+		{[]string{"1h30m"}, 90 * time.Minute, false},
+		{[]string{"-1h"}, 0, true},
+		{[]string{"soon"}, 0, true},
+		{[]string{"1h", "2h"}, 0, true},
+	}
+
+	for _, test := range tests {
+		d, err := ParseMuteDuration(test.argv)
+		if (err != nil) != test.err {
+			t.Errorf("%v: unexpected error %v", test.argv, err)
+		}
+		if d != test.expect {
+			t.Errorf("%v: expected %v, got %v", test.argv, test.expect, d)
+		}
+	}
+}
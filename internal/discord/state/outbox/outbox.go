@@ -0,0 +1,395 @@
+// Package outbox provides a queue of messages that failed to send and are
+// retried with backoff.
+package outbox
+
+import (
+	"bytes"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/api"
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/utils/handler"
+	"github.com/diamondburned/arikawa/v2/utils/httputil"
+	"github.com/diamondburned/arikawa/v2/utils/sendpart"
+	"github.com/pkg/errors"
+)
+
+const (
+	// MaxAttempts is the number of attempts before a message is marked as
+	// failed.
+	MaxAttempts = 6
+	// BaseBackoff is the delay after the first failed attempt. It doubles
+	// after every attempt up to MaxBackoff.
+	BaseBackoff = 2 * time.Second
+	MaxBackoff  = time.Minute
+)
+
+// idPrefix is the prefix of the IDs of messages in the outbox.
+const idPrefix = "outbox:"
+
+// ID returns the message ID of the entry with the given nonce.
+func ID(nonce string) string { return idPrefix + nonce }
+
+// ParseID returns the nonce from the given message ID and true if the ID
+// belongs to a message in the outbox.
+func ParseID(id string) (string, bool) {
+	if !strings.HasPrefix(id, idPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(id, idPrefix), true
+}
+
+// Status is the status of a queued message.
+type Status uint8
+
+const (
+	// Pending messages are waiting to be retried.
+	Pending Status = iota
+	// Sending messages are being sent.
+	Sending
+	// Failed messages are no longer retried unless done so manually.
+	Failed
+)
+
+// Entry is a message in the outbox.
+type Entry struct {
+	// Nonce is the key of the entry. It is the nonce sent to Discord if there
+	// is one.
+	Nonce string
+	// Original is the nonce given by the frontend.
+	Original  string
+	ChannelID discord.ChannelID
+	Data      api.SendMessageData
+	Created   time.Time
+
+	Status   Status
+	Attempts int
+	Err      error
+	RetryAt  time.Time
+}
+
+// Update is an event dispatched whenever an entry changes. If Removed is true,
+// then the entry is no longer in the outbox. Sent is non-nil if it was removed
+// because it was sent.
+type Update struct {
+	Entry
+	Removed bool
+	Sent    *discord.Message
+}
+
+// Client is the API client used to send messages.
+type Client interface {
+	SendMessageComplex(discord.ChannelID, api.SendMessageData) (*discord.Message, error)
+}
+
+// Outbox is a per-session message queue.
+type Outbox struct {
+	*handler.Handler
+	client Client
+
+	mut     sync.Mutex
+	entries map[string]*entry
+	closed  bool
+}
+
+type entry struct {
+	Entry
+	files [][]byte
+	timer *time.Timer
+}
+
+// New creates a new outbox. Update events are dispatched synchronously in the
+// order that they happen.
+func New(client Client) *Outbox {
+	h := handler.New()
+	h.Synchronous = true
+
+	return &Outbox{
+		Handler: h,
+		client:  client,
+		entries: map[string]*entry{},
+	}
+}
+
+var keyCounter uint64
+
+// Send sends the message, queueing it for retrying if it fails with a
+// temporary error, such as being rate limited or a network failure. If the
+// message is queued, a nil message and a nil error are returned.
+func (o *Outbox) Send(chID discord.ChannelID, original string, data api.SendMessageData) (*discord.Message, error) {
+	e := &entry{
+		Entry: Entry{
+			Nonce:     data.Nonce,
+			Original:  original,
+			ChannelID: chID,
+			Data:      data,
+			Created:   time.Now(),
+			Status:    Sending,
+		},
+	}
+
+	if e.Nonce == "" {
+		e.Nonce = "local-" + strconv.FormatUint(atomic.AddUint64(&keyCounter, 1), 36)
+	}
+
+	// Files can only be read once, so we have to keep them around for
+	// retrying.
+	for _, file := range data.Files {
+		b, err := ioutil.ReadAll(file.Reader)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read attachment %q", file.Name)
+		}
+		e.files = append(e.files, b)
+	}
+
+	m, err := o.client.SendMessageComplex(chID, e.data())
+	if err == nil {
+		return m, nil
+	}
+
+	if !Temporary(err) {
+		return nil, err
+	}
+
+	o.mut.Lock()
+
+	if o.closed {
+		o.mut.Unlock()
+		return nil, err
+	}
+
+	o.entries[e.Nonce] = e
+	u := o.failed(e, err)
+
+	o.mut.Unlock()
+
+	o.Call(u)
+	return nil, nil
+}
+
+// Entries returns the entries queued for the given channel, sorted by their
+// creation time.
+func (o *Outbox) Entries(chID discord.ChannelID) []Entry {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	var entries []Entry
+	for _, e := range o.entries {
+		if e.ChannelID == chID {
+			entries = append(entries, e.Entry)
+		}
+	}
+
+	sortEntries(entries)
+	return entries
+}
+
+// Entry returns the entry with the given nonce.
+func (o *Outbox) Entry(nonce string) (Entry, bool) {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	e, ok := o.entries[nonce]
+	if !ok {
+		return Entry{}, false
+	}
+
+	return e.Entry, true
+}
+
+// Cancel removes the message with the given nonce from the outbox. A message
+// that is currently being sent cannot be cancelled.
+func (o *Outbox) Cancel(nonce string) error {
+	o.mut.Lock()
+
+	e, ok := o.entries[nonce]
+	if !ok {
+		o.mut.Unlock()
+		return errors.New("message is not in the outbox")
+	}
+
+	if e.Status == Sending {
+		o.mut.Unlock()
+		return errors.New("message is being sent")
+	}
+
+	e.stop()
+	delete(o.entries, nonce)
+
+	o.mut.Unlock()
+
+	o.Call(&Update{Entry: e.Entry, Removed: true})
+	return nil
+}
+
+// Retry retries sending the message with the given nonce immediately.
+func (o *Outbox) Retry(nonce string) error {
+	o.mut.Lock()
+	e, ok := o.entries[nonce]
+	o.mut.Unlock()
+
+	if !ok {
+		return errors.New("message is not in the outbox")
+	}
+
+	go o.retry(e)
+	return nil
+}
+
+// RetryPending immediately retries all pending messages. It should be called
+// when the connection is restored.
+func (o *Outbox) RetryPending() {
+	o.mut.Lock()
+
+	var pending []*entry
+	for _, e := range o.entries {
+		if e.Status == Pending {
+			pending = append(pending, e)
+		}
+	}
+
+	o.mut.Unlock()
+
+	for _, e := range pending {
+		go o.retry(e)
+	}
+}
+
+// Close stops retrying all messages and clears the outbox.
+func (o *Outbox) Close() {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	o.closed = true
+
+	for _, e := range o.entries {
+		e.stop()
+	}
+
+	o.entries = map[string]*entry{}
+}
+
+func (o *Outbox) retry(e *entry) {
+	o.mut.Lock()
+
+	// Make sure the entry is still queued and not already being sent.
+	if o.entries[e.Nonce] != e || e.Status == Sending {
+		o.mut.Unlock()
+		return
+	}
+
+	e.stop()
+	e.Status = Sending
+	data := e.data()
+	u := &Update{Entry: e.Entry}
+
+	o.mut.Unlock()
+
+	o.Call(u)
+
+	m, err := o.client.SendMessageComplex(e.ChannelID, data)
+
+	o.mut.Lock()
+
+	switch {
+	case o.entries[e.Nonce] != e:
+		// The outbox was closed in the meantime.
+		o.mut.Unlock()
+		return
+
+	case err == nil:
+		delete(o.entries, e.Nonce)
+		u = &Update{Entry: e.Entry, Removed: true, Sent: m}
+
+	default:
+		u = o.failed(e, err)
+	}
+
+	o.mut.Unlock()
+
+	o.Call(u)
+}
+
+// failed marks the entry as failed and schedules a retry if possible. The
+// caller must hold the mutex.
+func (o *Outbox) failed(e *entry, err error) *Update {
+	e.Attempts++
+	e.Err = err
+
+	if !Temporary(err) || e.Attempts >= MaxAttempts {
+		e.Status = Failed
+		e.RetryAt = time.Time{}
+		return &Update{Entry: e.Entry}
+	}
+
+	delay := Backoff(e.Attempts)
+
+	e.Status = Pending
+	e.RetryAt = time.Now().Add(delay)
+	e.timer = time.AfterFunc(delay, func() { o.retry(e) })
+
+	return &Update{Entry: e.Entry}
+}
+
+func (e *entry) stop() {
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+}
+
+// data returns the send data with new readers for the files.
+func (e *entry) data() api.SendMessageData {
+	data := e.Data
+
+	if len(e.files) > 0 {
+		data.Files = make([]sendpart.File, len(e.files))
+		for i, b := range e.files {
+			data.Files[i] = sendpart.File{
+				Name:   e.Data.Files[i].Name,
+				Reader: bytes.NewReader(b),
+			}
+		}
+	}
+
+	return data
+}
+
+func sortEntries(entries []Entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Created.Before(entries[j].Created)
+	})
+}
+
+// Backoff returns the delay before the next attempt after the given number of
+// attempts.
+func Backoff(attempts int) time.Duration {
+	delay := BaseBackoff
+	for i := 1; i < attempts && delay < MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > MaxBackoff {
+		delay = MaxBackoff
+	}
+
+	return delay
+}
+
+// Temporary returns true if the error is worth retrying. Rate limits, server
+// errors and network errors are temporary, while other API errors are not.
+func Temporary(err error) bool {
+	var httpErr *httputil.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Status == 429 || httpErr.Status >= 500
+	}
+
+	var reqErr httputil.RequestError
+	return errors.As(err, &reqErr)
+}
@@ -0,0 +1,144 @@
+package outbox
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/api"
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/utils/httputil"
+	"github.com/diamondburned/arikawa/v2/utils/sendpart"
+)
+
+type fakeClient struct {
+	mut   sync.Mutex
+	errs  []error
+	files []string
+}
+
+func (c *fakeClient) SendMessageComplex(
+	chID discord.ChannelID, data api.SendMessageData) (*discord.Message, error) {
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	for _, file := range data.Files {
+		b, _ := ioutil.ReadAll(file.Reader)
+		c.files = append(c.files, string(b))
+	}
+
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return nil, err
+	}
+
+	return &discord.Message{ID: 1, ChannelID: chID, Content: data.Content}, nil
+}
+
+func TestRetry(t *testing.T) {
+	client := &fakeClient{
+		errs: []error{&httputil.HTTPError{Status: 500}},
+	}
+
+	o := New(client)
+	defer o.Close()
+
+	updates := make(chan *Update, 10)
+	o.AddHandler(func(u *Update) { updates <- u })
+
+	m, err := o.Send(20, "original", api.SendMessageData{
+		Content: "hello",
+		Nonce:   "nonce",
+		Files:   []sendpart.File{{Name: "a.txt", Reader: strings.NewReader("file")}},
+	})
+	if err != nil || m != nil {
+		t.Fatalf("Expected the message to be queued, got %v, %v", m, err)
+	}
+
+	u := <-updates
+	if u.Status != Pending || u.Attempts != 1 || u.Original != "original" {
+		t.Fatalf("Unexpected update: %#v", u)
+	}
+
+	if entries := o.Entries(20); len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+
+	if err := o.Retry("nonce"); err != nil {
+		t.Fatal("Failed to retry:", err)
+	}
+
+	for u := range updates {
+		if u.Removed {
+			if u.Sent == nil || u.Sent.Content != "hello" {
+				t.Fatalf("Unexpected sent message: %#v", u.Sent)
+			}
+			break
+		}
+	}
+
+	if entries := o.Entries(20); len(entries) != 0 {
+		t.Fatalf("Expected no entries, got %d", len(entries))
+	}
+
+	// The attachment must be readable on every attempt.
+	if len(client.files) != 2 || client.files[1] != "file" {
+		t.Fatalf("Unexpected files sent: %q", client.files)
+	}
+}
+
+func TestPermanentError(t *testing.T) {
+	o := New(&fakeClient{
+		errs: []error{&httputil.HTTPError{Status: 400}},
+	})
+	defer o.Close()
+
+	if _, err := o.Send(20, "", api.SendMessageData{Content: "hello"}); err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	if entries := o.Entries(20); len(entries) != 0 {
+		t.Fatalf("Expected no entries, got %d", len(entries))
+	}
+}
+
+func TestCancel(t *testing.T) {
+	o := New(&fakeClient{
+		errs: []error{&httputil.HTTPError{Status: 429}},
+	})
+	defer o.Close()
+
+	if _, err := o.Send(20, "", api.SendMessageData{Content: "hello"}); err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+
+	entries := o.Entries(20)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+
+	if err := o.Cancel(entries[0].Nonce); err != nil {
+		t.Fatal("Failed to cancel:", err)
+	}
+
+	if entries := o.Entries(20); len(entries) != 0 {
+		t.Fatalf("Expected no entries, got %d", len(entries))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	var expect = []time.Duration{
+		2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second,
+		32 * time.Second, time.Minute, time.Minute,
+	}
+
+	for i, expect := range expect {
+		if got := Backoff(i + 1); got != expect {
+			t.Errorf("Backoff(%d) = %v, expected %v", i+1, got, expect)
+		}
+	}
+}
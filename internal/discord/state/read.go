@@ -0,0 +1,94 @@
+package state
+
+import (
+	"github.com/diamondburned/arikawa/v2/api"
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/pkg/errors"
+)
+
+// MarkGuildRead marks all channels in the guild as read with a single request.
+func (s *Instance) MarkGuildRead(guildID discord.GuildID) error {
+	channels, err := s.Channels(guildID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get channels")
+	}
+
+	unread := s.unreadChannels(channels)
+	if len(unread) == 0 {
+		return nil
+	}
+
+	if err := s.FastRequest("POST", api.EndpointGuilds+guildID.String()+"/ack"); err != nil {
+		return errors.Wrap(err, "failed to mark guild as read")
+	}
+
+	for _, ch := range unread {
+		s.acked(ch)
+	}
+
+	return nil
+}
+
+// MarkAllRead marks all guild and private channels as read. Guilds are
+// acknowledged as a whole, and private channels one by one. It stops at the
+// first failed request, so that being rate limited doesn't turn into a flood of
+// failing requests.
+func (s *Instance) MarkAllRead() error {
+	guilds, err := s.Guilds()
+	if err != nil {
+		return errors.Wrap(err, "failed to get guilds")
+	}
+
+	for _, g := range guilds {
+		if err := s.MarkGuildRead(g.ID); err != nil {
+			return err
+		}
+	}
+
+	channels, err := s.PrivateChannels()
+	if err != nil {
+		return errors.Wrap(err, "failed to get private channels")
+	}
+
+	var ack api.Ack
+
+	for _, ch := range s.unreadChannels(channels) {
+		if err := s.Ack(ch.ID, ch.LastMessageID, &ack); err != nil {
+			return errors.Wrap(err, "failed to mark private channel as read")
+		}
+
+		s.acked(ch)
+	}
+
+	return nil
+}
+
+// unreadChannels returns the channels that have unread messages.
+func (s *Instance) unreadChannels(channels []discord.Channel) []discord.Channel {
+	var unread []discord.Channel
+
+	for _, ch := range channels {
+		if !ch.LastMessageID.IsValid() {
+			continue
+		}
+
+		if last, ok := s.Unread.LastRead(ch.ID); ok && last >= ch.LastMessageID {
+			continue
+		}
+
+		unread = append(unread, ch)
+	}
+
+	return unread
+}
+
+// acked dispatches the acknowledgement of the channel's last message as if the
+// gateway had sent it, so that the read state is updated without sending
+// another request.
+func (s *Instance) acked(ch discord.Channel) {
+	s.Session.Handler.Call(&gateway.MessageAckEvent{
+		ChannelID: ch.ID,
+		MessageID: ch.LastMessageID,
+	})
+}
@@ -0,0 +1,197 @@
+package replay
+
+import (
+	"sync"
+	"time"
+
+	"github.com/diamondburned/cchat"
+)
+
+// recorder is embedded into fake containers to signal whenever they're
+// changed, since some containers are updated from other goroutines.
+type recorder struct {
+	mut     sync.Mutex
+	changed chan struct{}
+}
+
+func (r *recorder) lock() {
+	r.mut.Lock()
+	if r.changed == nil {
+		r.changed = make(chan struct{}, 1)
+	}
+}
+
+// unlockChanged unlocks the mutex and signals a change.
+func (r *recorder) unlockChanged() {
+	r.mut.Unlock()
+
+	select {
+	case r.changed <- struct{}{}:
+	default:
+	}
+}
+
+// waitFor calls fn with the mutex acquired every time the container changes
+// until fn returns true or until the timeout. It returns false on timeout.
+func (r *recorder) waitFor(timeout time.Duration, fn func() bool) bool {
+	r.lock()
+	ch := r.changed
+	ok := fn()
+	r.mut.Unlock()
+
+	if ok {
+		return true
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ch:
+		case <-timer.C:
+			return false
+		}
+
+		r.mut.Lock()
+		ok := fn()
+		r.mut.Unlock()
+
+		if ok {
+			return true
+		}
+	}
+}
+
+// MessagesContainer is a fake cchat.MessagesContainer that records all
+// messages.
+type MessagesContainer struct {
+	recorder
+	Created []cchat.MessageCreate
+	Updated []cchat.MessageUpdate
+	Deleted []cchat.MessageDelete
+}
+
+var _ cchat.MessagesContainer = (*MessagesContainer)(nil)
+
+func (c *MessagesContainer) CreateMessage(msg cchat.MessageCreate) {
+	c.lock()
+	c.Created = append(c.Created, msg)
+	c.unlockChanged()
+}
+
+func (c *MessagesContainer) UpdateMessage(msg cchat.MessageUpdate) {
+	c.lock()
+	c.Updated = append(c.Updated, msg)
+	c.unlockChanged()
+}
+
+func (c *MessagesContainer) DeleteMessage(msg cchat.MessageDelete) {
+	c.lock()
+	c.Deleted = append(c.Deleted, msg)
+	c.unlockChanged()
+}
+
+// WaitFor waits until fn returns true. Fn is called with the container locked.
+func (c *MessagesContainer) WaitFor(timeout time.Duration, fn func() bool) bool {
+	return c.waitFor(timeout, fn)
+}
+
+// ServersContainer is a fake cchat.ServersContainer that keeps the last list of
+// servers.
+type ServersContainer struct {
+	recorder
+	Servers []cchat.Server
+	Updated []cchat.ServerUpdate
+}
+
+var _ cchat.ServersContainer = (*ServersContainer)(nil)
+
+func (c *ServersContainer) SetServers(servers []cchat.Server) {
+	c.lock()
+	c.Servers = servers
+	c.unlockChanged()
+}
+
+func (c *ServersContainer) UpdateServer(update cchat.ServerUpdate) {
+	c.lock()
+	c.Updated = append(c.Updated, update)
+	c.unlockChanged()
+}
+
+// WaitFor waits until fn returns true. Fn is called with the container locked.
+func (c *ServersContainer) WaitFor(timeout time.Duration, fn func() bool) bool {
+	return c.waitFor(timeout, fn)
+}
+
+// UnreadContainer is a fake cchat.UnreadContainer that keeps the last unread
+// state.
+type UnreadContainer struct {
+	recorder
+	Unread    bool
+	Mentioned bool
+	Calls     int
+}
+
+var _ cchat.UnreadContainer = (*UnreadContainer)(nil)
+
+func (c *UnreadContainer) SetUnread(unread, mentioned bool) {
+	c.lock()
+	c.Unread = unread
+	c.Mentioned = mentioned
+	c.Calls++
+	c.unlockChanged()
+}
+
+// WaitFor waits until fn returns true. Fn is called with the container locked.
+func (c *UnreadContainer) WaitFor(timeout time.Duration, fn func() bool) bool {
+	return c.waitFor(timeout, fn)
+}
+
+// MemberListContainer is a fake cchat.MemberListContainer that keeps the
+// sections and members.
+type MemberListContainer struct {
+	recorder
+	Sections []cchat.MemberSection
+	Members  map[cchat.ID]map[cchat.ID]cchat.ListMember
+}
+
+var _ cchat.MemberListContainer = (*MemberListContainer)(nil)
+
+func (c *MemberListContainer) SetSections(sections []cchat.MemberSection) {
+	c.lock()
+	c.Sections = sections
+	c.unlockChanged()
+}
+
+func (c *MemberListContainer) SetMember(sectionID cchat.ID, member cchat.ListMember) {
+	c.lock()
+
+	if c.Members == nil {
+		c.Members = map[cchat.ID]map[cchat.ID]cchat.ListMember{}
+	}
+
+	section, ok := c.Members[sectionID]
+	if !ok {
+		section = map[cchat.ID]cchat.ListMember{}
+		c.Members[sectionID] = section
+	}
+
+	section[member.ID()] = member
+	c.unlockChanged()
+}
+
+func (c *MemberListContainer) RemoveMember(sectionID, memberID cchat.ID) {
+	c.lock()
+
+	if section, ok := c.Members[sectionID]; ok {
+		delete(section, memberID)
+	}
+
+	c.unlockChanged()
+}
+
+// WaitFor waits until fn returns true. Fn is called with the container locked.
+func (c *MemberListContainer) WaitFor(timeout time.Duration, fn func() bool) bool {
+	return c.waitFor(timeout, fn)
+}
@@ -0,0 +1,56 @@
+package replay
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// Fixture is a recorded session. It contains the READY payload, a sequence of
+// gateway events that follow it and canned REST responses.
+type Fixture struct {
+	Ready  json.RawMessage `json:"ready"`
+	Events []Event         `json:"events"`
+	REST   []Response      `json:"rest"`
+}
+
+// Event is a single gateway dispatch event, formatted the same way Discord
+// sends it over the Websocket.
+type Event struct {
+	Type string          `json:"t"`
+	Data json.RawMessage `json:"d"`
+}
+
+// Response is a canned REST response. Path is relative to the API root, e.g.
+// "/channels/1/messages". Query parameters are ignored when matching.
+type Response struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Status int             `json:"status,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// status returns the response status, defaulting to 200 OK if none.
+func (r Response) status() int {
+	if r.Status == 0 {
+		return http.StatusOK
+	}
+	return r.Status
+}
+
+// Load reads the JSON fixture at the given path.
+func Load(path string) (*Fixture, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read fixture")
+	}
+
+	var f Fixture
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, errors.Wrap(err, "failed to parse fixture")
+	}
+
+	return &f, nil
+}
@@ -0,0 +1,217 @@
+// Package replay provides an offline harness that builds a state instance from
+// a recorded fixture instead of a live gateway connection. It is meant to be
+// used in tests.
+package replay
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v2/api"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/arikawa/v2/session"
+	arikawa "github.com/diamondburned/arikawa/v2/state"
+	"github.com/diamondburned/arikawa/v2/state/store/defaultstore"
+	"github.com/diamondburned/arikawa/v2/utils/httputil/httpdriver"
+	"github.com/diamondburned/cchat-discord/internal/discord/state"
+	"github.com/diamondburned/ningen/v2"
+	"github.com/pkg/errors"
+)
+
+// Token is the fake token used by the harness.
+const Token = "replay"
+
+// Request is a REST request received by the harness.
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Body   []byte
+}
+
+// Harness is an offline state instance backed by a fixture. All event handlers
+// are called synchronously, so the effects of a dispatched event are visible
+// as soon as Dispatch returns.
+type Harness struct {
+	*state.Instance
+	Fixture *Fixture
+
+	server  *httptest.Server
+	session *session.Session
+
+	mut      sync.Mutex
+	routes   map[string]Response
+	requests []Request
+}
+
+// New creates a new harness from the given fixture and dispatches its READY
+// event. The events in the fixture are not dispatched until Replay is called.
+func New(f *Fixture) (*Harness, error) {
+	h := &Harness{
+		Fixture: f,
+		routes:  make(map[string]Response, len(f.REST)),
+	}
+
+	for _, r := range f.REST {
+		h.routes[routeKey(r.Method, r.Path)] = r
+	}
+
+	h.server = httptest.NewServer(http.HandlerFunc(h.serveHTTP))
+
+	serverURL, err := url.Parse(h.server.URL)
+	if err != nil {
+		h.server.Close()
+		return nil, errors.Wrap(err, "failed to parse server URL")
+	}
+
+	// The custom gateway does not make any request nor connect until it is
+	// opened, which we never do.
+	gw := gateway.NewCustomGateway("ws://"+serverURL.Host, Token)
+
+	sess := session.NewWithGateway(gw)
+	sess.Handler.Synchronous = true
+	h.session = sess
+
+	// Redirect all API calls to the test server. Endpoints are global
+	// variables, so rewriting the transport is the least invasive way.
+	sess.Client.Client.Client = httpdriver.WrapClient(http.Client{
+		Transport: rewriteTransport{serverURL},
+	})
+	sess.Client.Client.Retries = 1
+
+	s := arikawa.NewFromSession(sess, defaultstore.New())
+	s.Handler.Synchronous = true
+
+	n, err := ningen.FromState(s)
+	if err != nil {
+		h.server.Close()
+		return nil, errors.Wrap(err, "failed to create a state wrapper")
+	}
+	n.Handler.Synchronous = true
+
+	if err := h.dispatch("READY", f.Ready); err != nil {
+		h.server.Close()
+		return nil, errors.Wrap(err, "failed to dispatch READY")
+	}
+
+	i, err := state.NewFromNingen(n)
+	if err != nil {
+		h.server.Close()
+		return nil, err
+	}
+
+	h.Instance = i
+	return h, nil
+}
+
+// Replay dispatches all events in the fixture in order.
+func (h *Harness) Replay() error {
+	for _, ev := range h.Fixture.Events {
+		if err := h.dispatch(ev.Type, ev.Data); err != nil {
+			return errors.Wrapf(err, "failed to dispatch %s", ev.Type)
+		}
+	}
+
+	return nil
+}
+
+// Dispatch dispatches a single gateway event with the given name, such as
+// "MESSAGE_CREATE". The data is marshaled into JSON and decoded the same way
+// the gateway would.
+func (h *Harness) Dispatch(name string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal event")
+	}
+
+	return h.dispatch(name, b)
+}
+
+func (h *Harness) dispatch(name string, data json.RawMessage) error {
+	fn, ok := gateway.EventCreator[name]
+	if !ok {
+		return errors.New("unknown event " + name)
+	}
+
+	ev := fn()
+
+	if err := json.Unmarshal(data, ev); err != nil {
+		return errors.Wrap(err, "failed to unmarshal event")
+	}
+
+	// Events go through the session handler, the same way they would come
+	// from the gateway.
+	h.session.Handler.Call(ev)
+	return nil
+}
+
+// Requests returns a copy of all REST requests received so far.
+func (h *Harness) Requests() []Request {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	requests := make([]Request, len(h.requests))
+	copy(requests, h.requests)
+	return requests
+}
+
+// Close stops the test server.
+func (h *Harness) Close() {
+	h.server.Close()
+}
+
+func (h *Harness) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, api.Path)
+
+	body, _ := ioutil.ReadAll(r.Body)
+
+	h.mut.Lock()
+	h.requests = append(h.requests, Request{
+		Method: r.Method,
+		Path:   path,
+		Query:  r.URL.Query(),
+		Body:   body,
+	})
+	resp, ok := h.routes[routeKey(r.Method, path)]
+	h.mut.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"code":0,"message":"404: Not Found"}`))
+		return
+	}
+
+	if len(resp.Body) == 0 && resp.Status == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.WriteHeader(resp.status())
+	w.Write(resp.Body)
+}
+
+func routeKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// rewriteTransport sends all requests to the given URL while keeping the path
+// and query.
+type rewriteTransport struct {
+	url *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme = t.url.Scheme
+	r.URL.Host = t.url.Host
+	r.Host = t.url.Host
+
+	return http.DefaultTransport.RoundTrip(r)
+}
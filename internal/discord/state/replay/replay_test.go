@@ -0,0 +1,534 @@
+package replay_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/cchat-discord/internal/discord/channel/message"
+	"github.com/diamondburned/cchat-discord/internal/discord/channel/message/action"
+	"github.com/diamondburned/cchat-discord/internal/discord/channel/message/indicate"
+	"github.com/diamondburned/cchat-discord/internal/discord/channel/message/memberlist"
+	"github.com/diamondburned/cchat-discord/internal/discord/channel/shared"
+	"github.com/diamondburned/cchat-discord/internal/discord/private/hub"
+	"github.com/diamondburned/cchat-discord/internal/discord/state"
+	"github.com/diamondburned/cchat-discord/internal/discord/state/replay"
+	"github.com/diamondburned/cchat/utils/empty"
+)
+
+const timeout = 2 * time.Second
+
+func newHarness(t *testing.T) *replay.Harness {
+	t.Helper()
+
+	f, err := replay.Load("testdata/basic.json")
+	if err != nil {
+		t.Fatal("Failed to load fixture:", err)
+	}
+
+	h, err := replay.New(f)
+	if err != nil {
+		t.Fatal("Failed to create harness:", err)
+	}
+
+	t.Cleanup(h.Close)
+	return h
+}
+
+func TestMessengerJoinServer(t *testing.T) {
+	h := newHarness(t)
+
+	msgr := message.New(shared.Channel{ID: 20, GuildID: 10, State: h.Instance})
+	ct := &replay.MessagesContainer{}
+
+	stop, err := msgr.JoinServer(context.Background(), ct)
+	if err != nil {
+		t.Fatal("Failed to join server:", err)
+	}
+
+	var expect = []string{"first", "second"}
+
+	if len(ct.Created) != len(expect) {
+		t.Fatalf("Expected %d backlog messages, got %d", len(expect), len(ct.Created))
+	}
+
+	for i, content := range expect {
+		if got := ct.Created[i].Content().Content; got != content {
+			t.Errorf("Backlog message %d: expected %q, got %q", i, content, got)
+		}
+	}
+
+	if err := h.Replay(); err != nil {
+		t.Fatal("Failed to replay:", err)
+	}
+
+	if len(ct.Created) != 3 {
+		t.Fatalf("Expected 3 messages after replay, got %d", len(ct.Created))
+	}
+
+	if got := ct.Created[2].Content().Content; got != "replayed" {
+		t.Errorf("Expected replayed message, got %q", got)
+	}
+
+	stop()
+
+	err = h.Dispatch("MESSAGE_CREATE", discord.Message{
+		ID:        203,
+		ChannelID: 20,
+		GuildID:   10,
+		Author:    discord.User{ID: 2, Username: "friend"},
+		Content:   "after leaving",
+	})
+	if err != nil {
+		t.Fatal("Failed to dispatch:", err)
+	}
+
+	if len(ct.Created) != 3 {
+		t.Errorf("Container received messages after stopping: %d", len(ct.Created))
+	}
+}
+
+func TestHubMessages(t *testing.T) {
+	h := newHarness(t)
+
+	srv, err := hub.New(h.Instance, nil)
+	if err != nil {
+		t.Fatal("Failed to create hub:", err)
+	}
+	defer srv.Close()
+
+	if ids := srv.ActiveChannelIDs(); len(ids) != 0 {
+		t.Fatalf("Expected no active DM channels, got %v", ids)
+	}
+
+	if err := h.Replay(); err != nil {
+		t.Fatal("Failed to replay:", err)
+	}
+
+	// Messages received before joining are kept in the hub.
+	ct := &replay.MessagesContainer{}
+
+	stop, err := srv.AsMessenger().JoinServer(context.Background(), ct)
+	if err != nil {
+		t.Fatal("Failed to join hub:", err)
+	}
+	defer stop()
+
+	if len(ct.Created) != 1 {
+		t.Fatalf("Expected 1 DM message, got %d", len(ct.Created))
+	}
+
+	if got := ct.Created[0].Content().Content; got != "hello in DMs" {
+		t.Errorf("Unexpected DM content %q", got)
+	}
+}
+
+type channelAdder struct{ added []discord.ChannelID }
+
+func (a *channelAdder) AddChannel(_ *state.Instance, ch *discord.Channel) {
+	a.added = append(a.added, ch.ID)
+}
+
+type sendable struct {
+	empty.SendableMessage
+	content string
+}
+
+func (s sendable) Content() string { return s.content }
+
+func TestHubSend(t *testing.T) {
+	f, err := replay.Load("testdata/basic.json")
+	if err != nil {
+		t.Fatal("Failed to load fixture:", err)
+	}
+
+	f.REST = append(f.REST, replay.Response{
+		Method: "POST",
+		Path:   "/channels/30/messages",
+		Status: 503,
+		Body:   json.RawMessage(`{"code": 0, "message": "unavailable"}`),
+	})
+
+	h, err := replay.New(f)
+	if err != nil {
+		t.Fatal("Failed to create harness:", err)
+	}
+	defer h.Close()
+	defer h.Outbox.Close()
+
+	adder := &channelAdder{}
+
+	srv, err := hub.New(h.Instance, adder)
+	if err != nil {
+		t.Fatal("Failed to create hub:", err)
+	}
+	defer srv.Close()
+
+	sender := srv.AsMessenger().AsSender()
+
+	// Temporary failures queue the message instead of failing.
+	if err := sender.Send(sendable{content: "<#30> hello"}); err != nil {
+		t.Fatal("Failed to send:", err)
+	}
+
+	entries := h.Outbox.Entries(30)
+	if len(entries) != 1 || entries[0].Data.Content != "hello" {
+		t.Fatalf("Expected the message to be queued, got %#v", entries)
+	}
+
+	if len(adder.added) != 1 || adder.added[0] != 30 {
+		t.Errorf("Expected the DM channel to be added, got %v", adder.added)
+	}
+}
+
+func TestUnreadIndicator(t *testing.T) {
+	h := newHarness(t)
+
+	unread := indicate.NewUnread(shared.Channel{ID: 20, GuildID: 10, State: h.Instance})
+	ct := &replay.UnreadContainer{}
+
+	stop, err := unread.UnreadIndicate(ct)
+	if err != nil {
+		t.Fatal("Failed to indicate:", err)
+	}
+	defer stop()
+
+	if !ct.Unread || !ct.Mentioned {
+		t.Fatalf("Expected unread and mentioned, got %v and %v", ct.Unread, ct.Mentioned)
+	}
+
+	h.ReadState.MarkRead(20, 201)
+
+	// Read state callbacks are called in a separate goroutine.
+	ok := ct.WaitFor(timeout, func() bool { return !ct.Unread })
+	if !ok {
+		t.Fatal("Timed out waiting for the channel to be read")
+	}
+
+	if ct.Mentioned {
+		t.Error("Channel is still mentioned after being read")
+	}
+}
+
+func TestUnreadIndicatorMuted(t *testing.T) {
+	h := newHarness(t)
+
+	unread := indicate.NewUnread(shared.Channel{ID: 21, GuildID: 10, State: h.Instance})
+	ct := &replay.UnreadContainer{}
+
+	stop, err := unread.UnreadIndicate(ct)
+	if err != nil {
+		t.Fatal("Failed to indicate:", err)
+	}
+	defer stop()
+
+	if ct.Calls > 0 {
+		t.Fatalf("Muted channel was indicated as unread %d times", ct.Calls)
+	}
+}
+
+func TestMemberList(t *testing.T) {
+	h := newHarness(t)
+
+	lister := memberlist.New(shared.Channel{ID: 20, GuildID: 10, State: h.Instance})
+	ct := &replay.MemberListContainer{}
+
+	stop, err := lister.ListMembers(context.Background(), ct)
+	if err != nil {
+		t.Fatal("Failed to list members:", err)
+	}
+	defer stop()
+
+	friend, err := h.Cabinet.Member(10, 2)
+	if err != nil {
+		t.Fatal("Failed to get member:", err)
+	}
+
+	var item gateway.GuildMemberListOpItem
+	item.Member = &struct {
+		discord.Member
+		HoistedRole string           `json:"hoisted_role"`
+		Presence    gateway.Presence `json:"presence"`
+	}{Member: *friend}
+
+	item.Member.Presence.User = friend.User
+	item.Member.Presence.Status = gateway.OnlineStatus
+
+	err = h.Dispatch("GUILD_MEMBER_LIST_UPDATE", gateway.GuildMemberListUpdate{
+		ID:          "everyone",
+		GuildID:     10,
+		MemberCount: 2,
+		OnlineCount: 1,
+		Groups:      []gateway.GuildMemberListGroup{{ID: "online", Count: 1}},
+		Ops: []gateway.GuildMemberListOp{{
+			Op:    "SYNC",
+			Range: [2]int{0, 99},
+			Items: []gateway.GuildMemberListOpItem{
+				{Group: &gateway.GuildMemberListGroup{ID: "online", Count: 1}},
+				item,
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatal("Failed to dispatch:", err)
+	}
+
+	if len(ct.Sections) != 1 {
+		t.Fatalf("Expected 1 section, got %d", len(ct.Sections))
+	}
+
+	if _, ok := ct.Members["online"]["2"]; !ok {
+		t.Errorf("Member missing from the online section: %v", ct.Members)
+	}
+}
+
+func TestReplyResolution(t *testing.T) {
+	f, err := replay.Load("testdata/basic.json")
+	if err != nil {
+		t.Fatal("Failed to load fixture:", err)
+	}
+
+	f.REST = append(f.REST, replay.Response{
+		Method: "GET",
+		Path:   "/channels/20/messages/150",
+		Body: json.RawMessage(`{
+			"id": "150", "channel_id": "20", "content": "original",
+			"timestamp": "2021-01-01T00:00:00+00:00",
+			"author": {"id": "2", "username": "friend"}
+		}`),
+	})
+
+	h, err := replay.New(f)
+	if err != nil {
+		t.Fatal("Failed to create harness:", err)
+	}
+	defer h.Close()
+
+	msgr := message.New(shared.Channel{ID: 20, GuildID: 10, State: h.Instance})
+	ct := &replay.MessagesContainer{}
+
+	stop, err := msgr.JoinServer(context.Background(), ct)
+	if err != nil {
+		t.Fatal("Failed to join server:", err)
+	}
+	defer stop()
+
+	err = h.Dispatch("MESSAGE_CREATE", discord.Message{
+		ID:        203,
+		Type:      discord.InlinedReplyMessage,
+		ChannelID: 20,
+		GuildID:   10,
+		Author:    discord.User{ID: 1, Username: "me"},
+		Content:   "reply",
+		Reference: &discord.MessageReference{MessageID: 150},
+	})
+	if err != nil {
+		t.Fatal("Failed to dispatch:", err)
+	}
+
+	created := ct.WaitFor(timeout, func() bool { return len(ct.Created) == 3 })
+	if !created {
+		t.Fatal("Timed out waiting for the reply to be created")
+	}
+
+	if got := ct.Created[2].Content().Content; !strings.HasPrefix(got, ">Loading") {
+		t.Errorf("Expected a placeholder for the reply, got %q", got)
+	}
+
+	resolved := ct.WaitFor(timeout, func() bool {
+		for _, u := range ct.Updated {
+			if u.ID() == "203" && strings.HasPrefix(u.Content().Content, ">original\n") {
+				return true
+			}
+		}
+		return false
+	})
+
+	if !resolved {
+		t.Fatal("Reply was never resolved")
+	}
+}
+
+func TestShowContext(t *testing.T) {
+	h := newHarness(t)
+
+	msgr := message.New(shared.Channel{ID: 20, GuildID: 10, State: h.Instance})
+	ct := &replay.MessagesContainer{}
+
+	stop, err := msgr.JoinServer(context.Background(), ct)
+	if err != nil {
+		t.Fatal("Failed to join server:", err)
+	}
+	defer stop()
+
+	msg := func(id discord.MessageID) discord.Message {
+		return discord.Message{
+			ID:        id,
+			ChannelID: 20,
+			GuildID:   10,
+			Author:    discord.User{ID: 2, Username: "friend"},
+			Content:   id.String(),
+		}
+	}
+
+	// Messages in the state that were never shown, such as fetched replies,
+	// are still added.
+	if err := h.Cabinet.MessageSet(msg(198)); err != nil {
+		t.Fatal("Failed to set message:", err)
+	}
+
+	ev := &action.ContextEvent{
+		ChannelID: 20,
+		Messages:  []discord.Message{msg(201), msg(200), msg(199), msg(198)},
+	}
+
+	h.Call(ev)
+	h.Call(ev)
+
+	var expect = []string{"200", "201", "198", "199"}
+
+	if len(ct.Created) != len(expect) {
+		t.Fatalf("Expected %d messages, got %d", len(expect), len(ct.Created))
+	}
+
+	for i, id := range expect {
+		if got := ct.Created[i].ID(); got != id {
+			t.Errorf("Message %d: expected %s, got %s", i, id, got)
+		}
+	}
+}
+
+func TestReplyUnavailable(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		want   string
+	}{
+		{"deleted", 404, ">Original message was deleted.\n"},
+		{"forbidden", 403, ">Couldn't load replied message.\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := replay.Load("testdata/basic.json")
+			if err != nil {
+				t.Fatal("Failed to load fixture:", err)
+			}
+
+			f.REST = append(f.REST, replay.Response{
+				Method: "GET",
+				Path:   "/channels/20/messages/150",
+				Status: test.status,
+				Body:   json.RawMessage(`{"code": 0, "message": "error"}`),
+			})
+
+			h, err := replay.New(f)
+			if err != nil {
+				t.Fatal("Failed to create harness:", err)
+			}
+			defer h.Close()
+
+			msgr := message.New(shared.Channel{ID: 20, GuildID: 10, State: h.Instance})
+			ct := &replay.MessagesContainer{}
+
+			stop, err := msgr.JoinServer(context.Background(), ct)
+			if err != nil {
+				t.Fatal("Failed to join server:", err)
+			}
+			defer stop()
+
+			err = h.Dispatch("MESSAGE_CREATE", discord.Message{
+				ID:        203,
+				Type:      discord.InlinedReplyMessage,
+				ChannelID: 20,
+				GuildID:   10,
+				Author:    discord.User{ID: 1, Username: "me"},
+				Content:   "reply",
+				Reference: &discord.MessageReference{MessageID: 150},
+			})
+			if err != nil {
+				t.Fatal("Failed to dispatch:", err)
+			}
+
+			updated := ct.WaitFor(timeout, func() bool {
+				for _, u := range ct.Updated {
+					if u.ID() == "203" && strings.HasPrefix(u.Content().Content, test.want) {
+						return true
+					}
+				}
+				return false
+			})
+
+			if !updated {
+				t.Fatalf("Reply placeholder was never replaced with %q", test.want)
+			}
+		})
+	}
+}
+
+func TestMarkAllRead(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		acks   []string
+	}{
+		{"ok", 0, []string{"/guilds/10/ack", "/channels/30/messages/301/ack"}},
+		// The private channel isn't acknowledged once a request fails.
+		{"failed", 403, []string{"/guilds/10/ack"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := replay.Load("testdata/basic.json")
+			if err != nil {
+				t.Fatal("Failed to load fixture:", err)
+			}
+
+			r := replay.Response{Method: "POST", Path: "/guilds/10/ack", Status: test.status}
+			if test.status != 0 {
+				r.Body = json.RawMessage(`{"code": 0, "message": "error"}`)
+			}
+			f.REST = append(f.REST, r)
+
+			h, err := replay.New(f)
+			if err != nil {
+				t.Fatal("Failed to create harness:", err)
+			}
+			defer h.Close()
+
+			if err := h.Replay(); err != nil {
+				t.Fatal("Failed to replay:", err)
+			}
+
+			err = h.MarkAllRead()
+			if failed := err != nil; failed != (test.status != 0) {
+				t.Fatal("Unexpected error:", err)
+			}
+
+			var acks []string
+			for _, r := range h.Requests() {
+				if strings.HasSuffix(r.Path, "/ack") {
+					acks = append(acks, r.Path)
+				}
+			}
+
+			if strings.Join(acks, " ") != strings.Join(test.acks, " ") {
+				t.Fatalf("Expected acks %v, got %v", test.acks, acks)
+			}
+
+			if err != nil {
+				return
+			}
+
+			for chID, msgID := range map[discord.ChannelID]discord.MessageID{20: 202, 21: 211, 30: 301} {
+				if rs := h.ReadState.FindLast(chID); rs == nil || rs.LastMessageID != msgID {
+					t.Errorf("Channel %d is not read up to %d: %v", chID, msgID, rs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Package secret provides an encrypted format for saved sessions. The whole
+// session map is sealed with AES-256-GCM using a key derived from a passphrase,
+// so the token is never written by the frontend in plaintext.
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+// PassphraseEnv is the environment variable that the passphrase is read from.
+const PassphraseEnv = "CCHAT_DISCORD_PASSPHRASE"
+
+// Passphrase returns the passphrase to encrypt sessions with. Sessions are
+// saved in plaintext if it's empty.
+var Passphrase = func() string {
+	return os.Getenv(PassphraseEnv)
+}
+
+// Format is the value of the format key in encrypted sessions.
+const Format = "encrypted-v1"
+
+// Cost is the scrypt cost used for new sessions. Sessions keep the cost they
+// were encrypted with, so it can be raised without breaking old ones.
+var Cost = Params{N: 1 << 15, R: 8, P: 1}
+
+// Params are the scrypt cost parameters.
+type Params struct {
+	N, R, P int
+}
+
+// maxN is the largest N accepted when decrypting, so a tampered session can't
+// make us allocate gigabytes. Scrypt uses 128*N*R bytes.
+const maxN = 1 << 20
+
+func (p Params) valid() bool {
+	return p.N > 1 && p.N <= maxN && p.N&(p.N-1) == 0 &&
+		p.R > 0 && p.R <= 32 && p.P > 0 && p.P <= 16
+}
+
+const (
+	keyFormat = "format"
+	keyKDF    = "kdf"
+	keyN      = "n"
+	keyR      = "r"
+	keyP      = "p"
+	keySalt   = "salt"
+	keyNonce  = "nonce"
+	keyData   = "data"
+
+	kdfScrypt = "scrypt"
+	saltSize  = 16
+	keySize   = 32
+)
+
+var (
+	// ErrNoPassphrase is returned when decrypting without a passphrase.
+	ErrNoPassphrase = errors.New("session is encrypted, but " + PassphraseEnv + " is not set")
+	// ErrWrongPassphrase is returned if the session can't be decrypted.
+	ErrWrongPassphrase = errors.New("wrong passphrase or corrupted session")
+)
+
+// IsEncrypted returns true if the saved session is encrypted. Sessions saved
+// by older versions only have the plaintext token.
+func IsEncrypted(data map[string]string) bool {
+	_, ok := data[keyFormat]
+	return ok
+}
+
+// Encrypt seals the session data with the passphrase.
+func Encrypt(data map[string]string, passphrase string) (map[string]string, error) {
+	if passphrase == "" {
+		return nil, errors.New("empty passphrase")
+	}
+
+	plain, err := json.Marshal(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode session")
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errors.Wrap(err, "failed to generate salt")
+	}
+
+	aead, err := newAEAD(passphrase, salt, Cost)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "failed to generate nonce")
+	}
+
+	enc := base64.StdEncoding
+
+	return map[string]string{
+		keyFormat: Format,
+		keyKDF:    kdfScrypt,
+		keyN:      strconv.Itoa(Cost.N),
+		keyR:      strconv.Itoa(Cost.R),
+		keyP:      strconv.Itoa(Cost.P),
+		keySalt:   enc.EncodeToString(salt),
+		keyNonce:  enc.EncodeToString(nonce),
+		keyData:   enc.EncodeToString(aead.Seal(nil, nonce, plain, []byte(Format))),
+	}, nil
+}
+
+// Decrypt opens the session data sealed by Encrypt.
+func Decrypt(data map[string]string, passphrase string) (map[string]string, error) {
+	if data[keyFormat] != Format {
+		return nil, errors.Errorf("unknown session format %q", data[keyFormat])
+	}
+
+	if data[keyKDF] != kdfScrypt {
+		return nil, errors.Errorf("unknown key derivation %q", data[keyKDF])
+	}
+
+	if passphrase == "" {
+		return nil, ErrNoPassphrase
+	}
+
+	cost, err := parseParams(data)
+	if err != nil {
+		return nil, err
+	}
+
+	enc := base64.StdEncoding
+
+	salt, err := enc.DecodeString(data[keySalt])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid salt")
+	}
+
+	nonce, err := enc.DecodeString(data[keyNonce])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid nonce")
+	}
+
+	sealed, err := enc.DecodeString(data[keyData])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid data")
+	}
+
+	aead, err := newAEAD(passphrase, salt, cost)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce")
+	}
+
+	plain, err := aead.Open(nil, nonce, sealed, []byte(Format))
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	var session map[string]string
+	if err := json.Unmarshal(plain, &session); err != nil {
+		return nil, errors.Wrap(err, "failed to decode session")
+	}
+
+	return session, nil
+}
+
+// parseParams parses the scrypt parameters of an encrypted session.
+func parseParams(data map[string]string) (Params, error) {
+	var cost Params
+
+	for key, v := range map[string]*int{keyN: &cost.N, keyR: &cost.R, keyP: &cost.P} {
+		i, err := strconv.Atoi(data[key])
+		if err != nil {
+			return cost, errors.Wrapf(err, "invalid scrypt parameter %q", key)
+		}
+		*v = i
+	}
+
+	if !cost.valid() {
+		return cost, errors.Errorf("unsupported scrypt parameters %+v", cost)
+	}
+
+	return cost, nil
+}
+
+func newAEAD(passphrase string, salt []byte, cost Params) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, cost.N, cost.R, cost.P, keySize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to derive key")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cipher")
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,98 @@
+package secret
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+func TestScrypt(t *testing.T) {
+	var tests = []struct {
+		name       string
+		passphrase string
+		salt       string
+		cost       Params
+		keyLen     int
+		expect     string
+	}{{
+		// Test vector from RFC 7914, section 12.
+		name:       "rfc7914",
+		passphrase: "pleaseletmein",
+		salt:       "SodiumChloride",
+		cost:       Params{N: 16384, R: 8, P: 1},
+		keyLen:     64,
+		expect: "7023bdcb3afd7348461c06cd81fd38ebfda8fbba904f8e3ea9b543f6545da1f2" +
+			"d5432955613f0fcf62d49705242a9af9e61e85dc0d651e40dfcf017b45575887",
+	}, {
+		// Generated with Python's hashlib.scrypt at the cost used for new
+		// sessions.
+		name:       "session",
+		passphrase: "hunter2",
+		salt:       "cchat-discord",
+		cost:       Params{N: 32768, R: 8, P: 1},
+		keyLen:     keySize,
+		expect:     "27fbb4dfdb4c34ea7a3235e6d1e2d04b83dccbfd6b87d62f79b7bc15fb763b2e",
+	}}
+
+	if tests[1].cost != Cost {
+		t.Fatalf("Cost changed to %+v, update the test vector", Cost)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := test.cost
+
+			dk, err := scrypt.Key([]byte(test.passphrase), []byte(test.salt), c.N, c.R, c.P, test.keyLen)
+			if err != nil {
+				t.Fatal("Failed to derive key:", err)
+			}
+
+			if got := hex.EncodeToString(dk); got != test.expect {
+				t.Fatalf("Unexpected key:\n%s\nexpected:\n%s", got, test.expect)
+			}
+		})
+	}
+}
+
+func TestEncrypt(t *testing.T) {
+	session := map[string]string{"token": "secret token"}
+
+	sealed, err := Encrypt(session, "hunter2")
+	if err != nil {
+		t.Fatal("Failed to encrypt:", err)
+	}
+
+	if !IsEncrypted(sealed) || IsEncrypted(session) {
+		t.Fatal("Unexpected IsEncrypted result")
+	}
+
+	for k, v := range sealed {
+		if v == "secret token" {
+			t.Fatalf("Token is stored in plaintext at %q", k)
+		}
+	}
+
+	if _, err := Decrypt(sealed, "hunter3"); err != ErrWrongPassphrase {
+		t.Fatal("Expected ErrWrongPassphrase, got", err)
+	}
+
+	if _, err := Decrypt(sealed, ""); err != ErrNoPassphrase {
+		t.Fatal("Expected ErrNoPassphrase, got", err)
+	}
+
+	opened, err := Decrypt(sealed, "hunter2")
+	if err != nil {
+		t.Fatal("Failed to decrypt:", err)
+	}
+
+	if opened["token"] != "secret token" {
+		t.Fatalf("Unexpected decrypted session: %v", opened)
+	}
+
+	sealed[keyN] = "1073741824"
+
+	if _, err := Decrypt(sealed, "hunter2"); err == nil {
+		t.Fatal("Expected an excessive cost to be rejected")
+	}
+}
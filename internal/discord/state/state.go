@@ -4,14 +4,24 @@ package state
 import (
 	"context"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
 	"github.com/diamondburned/arikawa/v2/session"
 	"github.com/diamondburned/arikawa/v2/state"
+	"github.com/diamondburned/arikawa/v2/state/store"
 	"github.com/diamondburned/arikawa/v2/state/store/defaultstore"
 	"github.com/diamondburned/arikawa/v2/utils/httputil/httpdriver"
 	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-discord/internal/discord/config"
+	"github.com/diamondburned/cchat-discord/internal/discord/state/diskstore"
 	"github.com/diamondburned/cchat-discord/internal/discord/state/nonce"
+	"github.com/diamondburned/cchat-discord/internal/discord/state/outbox"
+	"github.com/diamondburned/cchat-discord/internal/discord/state/secret"
+	"github.com/diamondburned/cchat-discord/internal/discord/state/unread"
 	"github.com/diamondburned/ningen/v2"
 	"github.com/pkg/errors"
 )
@@ -20,6 +30,22 @@ type Instance struct {
 	*ningen.State
 	Nonces *nonce.Map
 
+	// MessageCache is the on-disk message store. It is nil if messages are
+	// only kept in memory.
+	MessageCache *diskstore.Message
+
+	// Outbox queues messages that failed to send for retrying.
+	Outbox *outbox.Outbox
+
+	// Overrides are this session's own values of the global configuration
+	// and its per-guild and per-channel overrides. They're saved with the
+	// session.
+	Overrides *config.Overrides
+
+	// Unread tracks channels marked unread. Messages should be marked as read
+	// through it instead of ReadState.
+	Unread *unread.Tracker
+
 	// UserID is a constant user ID of the current user. It is guaranteed to be
 	// valid.
 	UserID discord.UserID
@@ -30,22 +56,57 @@ var _ cchat.SessionSaver = (*Instance)(nil)
 // ErrInvalidSession is returned if SessionRestore is given a bad session.
 var ErrInvalidSession = errors.New("invalid session")
 
+// overridePrefix is the prefix of the saved session keys that hold the
+// configuration overrides.
+const overridePrefix = "override/"
+
+// NewFromData restores a saved session. Both encrypted sessions and plaintext
+// sessions saved by older versions are accepted; the latter are encrypted the
+// next time they're saved if a passphrase is set.
 func NewFromData(data map[string]string) (*Instance, error) {
+	return restore(data, NewFromToken)
+}
+
+// restore restores a saved session, creating the instance from the token with
+// the given function.
+func restore(data map[string]string, newFromToken func(string) (*Instance, error)) (*Instance, error) {
+	if secret.IsEncrypted(data) {
+		d, err := secret.Decrypt(data, secret.Passphrase())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decrypt session")
+		}
+		data = d
+	}
+
 	tk, ok := data["token"]
 	if !ok {
 		return nil, ErrInvalidSession
 	}
 
-	return NewFromToken(tk)
+	i, err := newFromToken(tk)
+	if err != nil {
+		return nil, err
+	}
+
+	var overrides = map[string]string{}
+	for k, v := range data {
+		if strings.HasPrefix(k, overridePrefix) {
+			overrides[strings.TrimPrefix(k, overridePrefix)] = v
+		}
+	}
+
+	i.Overrides.Load(overrides)
+
+	return i, nil
 }
 
 func NewFromToken(token string) (*Instance, error) {
-	s, err := state.New(token)
+	s, err := session.New(token)
 	if err != nil {
 		return nil, err
 	}
 
-	return New(s)
+	return NewFromSession(s)
 }
 
 func Login(email, password, mfa string) (*Instance, error) {
@@ -54,10 +115,71 @@ func Login(email, password, mfa string) (*Instance, error) {
 		return nil, err
 	}
 
+	return NewFromSession(session)
+}
+
+// NewFromSession creates a new instance from the session. The current user is
+// fetched first, so that the instance's caches are kept separate from the
+// other accounts'.
+func NewFromSession(s *session.Session) (*Instance, error) {
+	u, err := s.Me()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get current user")
+	}
+
+	cabinet := NewCabinet(u.ID)
+
+	i, err := New(state.NewFromSession(s, cabinet))
+	if err != nil {
+		// Stop writing the message cache, if there's one.
+		if m, ok := cabinet.MessageStore.(*diskstore.Message); ok {
+			m.Close()
+		}
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// MaxMessages is the maximum number of messages kept in the state per channel.
+const MaxMessages = 50
+
+// MessageCacheDir is the directory that messages are cached in if the message
+// cache is enabled in the configuration. Messages are only kept in memory if
+// this is empty.
+var MessageCacheDir = defaultMessageCacheDir()
+
+func defaultMessageCacheDir() string {
+	d, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(d, "cchat-discord", "messages")
+}
+
+// NewCabinet creates a new store cabinet for the given user. Messages are
+// cached on disk in a directory for the user if the message cache is enabled
+// and MessageCacheDir is set, otherwise they're kept in memory. The cached
+// messages are stored in plain text.
+func NewCabinet(userID discord.UserID) store.Cabinet {
 	cabinet := defaultstore.New()
-	cabinet.MessageStore = defaultstore.NewMessage(50)
+	cabinet.MessageStore = defaultstore.NewMessage(MaxMessages)
+
+	if MessageCacheDir == "" || !config.MessageCache() {
+		return cabinet
+	}
+
+	dir := filepath.Join(MessageCacheDir, userID.String())
 
-	return New(state.NewFromSession(session, cabinet))
+	m, err := diskstore.NewMessage(dir, MaxMessages)
+	if err != nil {
+		log.Println("[Discord] Failed to make message cache, using memory:", err)
+		return cabinet
+	}
+
+	cabinet.MessageStore = m
+	return cabinet
 }
 
 func New(s *state.State) (*Instance, error) {
@@ -75,19 +197,53 @@ func New(s *state.State) (*Instance, error) {
 		return nil, err
 	}
 
+	return NewFromNingen(n)
+}
+
+// NewFromNingen creates a new instance from an already opened ningen state.
+// It does not open the gateway, which allows the state to be fed events
+// manually.
+func NewFromNingen(n *ningen.State) (*Instance, error) {
 	// Prefetch user.
-	u, err := s.Me()
+	u, err := n.Me()
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get current user")
 	}
 
+	// Use the message cache if the state has one.
+	cache, _ := n.Cabinet.MessageStore.(*diskstore.Message)
+
+	o := outbox.New(n)
+
+	// Retry pending messages as soon as we're connected again.
+	n.AddHandler(func(*ningen.Connected) { o.RetryPending() })
+
+	t := unread.New(n.ReadState, n)
+	n.AddHandler(func(a *gateway.MessageAckEvent) { t.Acked(a.ChannelID, a.MessageID) })
+
 	return &Instance{
-		UserID: u.ID,
-		State:  n,
-		Nonces: new(nonce.Map),
+		UserID:       u.ID,
+		State:        n,
+		Nonces:       new(nonce.Map),
+		MessageCache: cache,
+		Outbox:       o,
+		Overrides:    config.World.NewOverrides(),
+		Unread:       t,
 	}, nil
 }
 
+// Close stops retrying queued messages, writes the message cache and closes the
+// gateway.
+func (s *Instance) Close() error {
+	s.Outbox.Close()
+
+	if s.MessageCache != nil {
+		s.MessageCache.Close()
+	}
+
+	return s.State.Close()
+}
+
 // Permissions queries for the permission without hitting the REST API.
 func (s *Instance) Permissions(
 	chID discord.ChannelID, uID discord.UserID) (discord.Permissions, error) {
@@ -103,8 +259,28 @@ func (s *Instance) StateOnly() *state.State {
 	return s.WithContext(ctx)
 }
 
+// SaveSession saves the token and the configuration overrides of this
+// session. The session is encrypted if a passphrase is set.
 func (s *Instance) SaveSession() map[string]string {
-	return map[string]string{
+	var data = map[string]string{
 		"token": s.Token,
 	}
+
+	for k, v := range s.Overrides.Save() {
+		data[overridePrefix+k] = v
+	}
+
+	passphrase := secret.Passphrase()
+	if passphrase == "" {
+		return data
+	}
+
+	sealed, err := secret.Encrypt(data, passphrase)
+	if err != nil {
+		// Never fall back to saving the token in plaintext.
+		log.Println("[Discord] Failed to encrypt session:", err)
+		return nil
+	}
+
+	return sealed
 }
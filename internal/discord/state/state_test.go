@@ -0,0 +1,122 @@
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v2/api"
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/session"
+	"github.com/diamondburned/arikawa/v2/state"
+	"github.com/diamondburned/cchat-discord/internal/discord/config"
+	"github.com/diamondburned/cchat-discord/internal/discord/state/diskstore"
+	"github.com/diamondburned/cchat-discord/internal/discord/state/secret"
+	"github.com/diamondburned/ningen/v2"
+)
+
+// newOffline creates an instance that only has a token and overrides.
+func newOffline(token string) (*Instance, error) {
+	return &Instance{
+		State: &ningen.State{
+			State: &state.State{
+				Session: &session.Session{Client: api.NewClient(token)},
+			},
+		},
+		Overrides: config.World.NewOverrides(),
+	}, nil
+}
+
+func TestRestoreAccounts(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+	}{
+		{"plaintext", ""},
+		{"encrypted", "hunter2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			secret.Passphrase = func() string { return test.passphrase }
+			defer func() { secret.Passphrase = func() string { return "" } }()
+
+			accounts := map[string]discord.ChannelID{"token a": 100, "token b": 200}
+			saved := map[string]map[string]string{}
+
+			for token, chID := range accounts {
+				i, _ := newOffline(token)
+				i.Overrides.Channel(chID).SetConfiguration(map[string]string{
+					"Mention on Reply": config.OverrideOn,
+				})
+				if token == "token a" {
+					i.Overrides.Account().SetConfiguration(map[string]string{
+						"Broadcast Typing": "false",
+					})
+				}
+
+				saved[token] = i.SaveSession()
+
+				if encrypted := secret.IsEncrypted(saved[token]); encrypted != (test.passphrase != "") {
+					t.Fatalf("Expected encrypted to be %t", !encrypted)
+				}
+			}
+
+			for token := range accounts {
+				i, err := restore(saved[token], newOffline)
+				if err != nil {
+					t.Fatalf("Failed to restore %q: %v", token, err)
+				}
+
+				if i.Token != token {
+					t.Errorf("Expected token %q, got %q", token, i.Token)
+				}
+
+				if typing := config.BroadcastTyping(i.Overrides, 0, 0); typing != (token != "token a") {
+					t.Errorf("Account %q: unexpected Broadcast Typing %t", token, typing)
+				}
+
+				for otherToken, otherID := range accounts {
+					cfg, _ := i.Overrides.Channel(otherID).Configuration()
+
+					expect := config.OverrideDefault
+					if otherToken == token {
+						expect = config.OverrideOn
+					}
+
+					if got := cfg["Mention on Reply"]; got != expect {
+						t.Errorf("Account %q: expected channel %d to be %q, got %q",
+							token, otherID, expect, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNewCabinet(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "cabinet-")
+	if err != nil {
+		t.Fatal("Failed to create temporary directory:", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	dir := MessageCacheDir
+	MessageCacheDir = tmp
+	defer func() { MessageCacheDir = dir }()
+
+	// The message cache is opt-in.
+	if _, ok := NewCabinet(1).MessageStore.(*diskstore.Message); ok {
+		t.Fatal("Messages are cached on disk without being enabled")
+	}
+
+	config.World.SetConfiguration(map[string]string{"Cache Messages on Disk": "true"})
+	defer config.World.SetConfiguration(map[string]string{"Cache Messages on Disk": ""})
+
+	m, ok := NewCabinet(1).MessageStore.(*diskstore.Message)
+	if !ok {
+		t.Fatal("Messages aren't cached on disk after being enabled")
+	}
+
+	m.Close()
+}
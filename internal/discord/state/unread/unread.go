@@ -0,0 +1,212 @@
+// Package unread wraps the read state to allow marking channels as unread,
+// which the read state can't do since it only moves forward, and to hold off
+// marking messages as read while the frontend is unfocused.
+package unread
+
+import (
+	"log"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v2/api"
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/arikawa/v2/utils/handler"
+	"github.com/diamondburned/arikawa/v2/utils/httputil"
+)
+
+// ReadState is the read state that the tracker wraps.
+type ReadState interface {
+	MarkRead(chID discord.ChannelID, msgID discord.MessageID)
+	FindLast(chID discord.ChannelID) *gateway.ReadState
+}
+
+// Client is used to acknowledge messages that the read state would skip.
+type Client interface {
+	FastRequest(method, url string, opts ...httputil.RequestOption) error
+}
+
+// UpdateEvent is sent when a channel is marked unread, or when it is marked
+// read again after that.
+type UpdateEvent struct {
+	ChannelID discord.ChannelID
+	Unread    bool
+}
+
+// Tracker tracks the channels that were marked unread by the user.
+type Tracker struct {
+	*handler.Handler
+	read   ReadState
+	client Client
+
+	mutex sync.Mutex
+	// focused is whether the frontend is focused.
+	focused bool
+	// pending has the messages to mark as read once focused.
+	pending map[discord.ChannelID]discord.MessageID
+	// marked has the last read message of the channels marked unread.
+	marked map[discord.ChannelID]discord.MessageID
+}
+
+func New(read ReadState, client Client) *Tracker {
+	h := handler.New()
+	h.Synchronous = true
+
+	return &Tracker{
+		Handler: h,
+		read:    read,
+		client:  client,
+		focused: true,
+		pending: map[discord.ChannelID]discord.MessageID{},
+		marked:  map[discord.ChannelID]discord.MessageID{},
+	}
+}
+
+// SetFocused sets whether the frontend is focused. Messages are only marked as
+// read automatically while it is; the ones that arrived while it wasn't are
+// marked once it is focused again. The frontend is focused by default.
+func (t *Tracker) SetFocused(focused bool) {
+	t.mutex.Lock()
+	changed := t.focused != focused
+	t.focused = focused
+	t.mutex.Unlock()
+
+	if changed && focused {
+		t.flush()
+	}
+}
+
+// Focused returns whether the frontend is focused.
+func (t *Tracker) Focused() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.focused
+}
+
+// AutoMarkRead marks the message as read if the frontend is focused. If it
+// isn't, the message is marked as read once it is.
+func (t *Tracker) AutoMarkRead(chID discord.ChannelID, msgID discord.MessageID) {
+	t.mutex.Lock()
+	if !t.focused {
+		if t.pending[chID] < msgID {
+			t.pending[chID] = msgID
+		}
+		t.mutex.Unlock()
+		return
+	}
+	t.mutex.Unlock()
+
+	t.MarkRead(chID, msgID)
+}
+
+// MarkRead marks the message as read.
+func (t *Tracker) MarkRead(chID discord.ChannelID, msgID discord.MessageID) {
+	t.mutex.Lock()
+	last, marked := t.marked[chID]
+	if marked && msgID > last {
+		delete(t.marked, chID)
+	}
+	if t.pending[chID] <= msgID {
+		delete(t.pending, chID)
+	}
+	t.mutex.Unlock()
+
+	// The read state ignores messages older than the ones that it has seen as
+	// read, so channels marked unread are acknowledged here.
+	if marked && msgID > last {
+		go func() {
+			if err := t.ack(chID, msgID, false); err != nil {
+				log.Println("[Discord] Failed to mark as read:", err)
+			}
+		}()
+
+		t.Call(&UpdateEvent{ChannelID: chID, Unread: false})
+	}
+
+	t.read.MarkRead(chID, msgID)
+}
+
+// MarkUnread marks the channel as unread from the given message onwards.
+func (t *Tracker) MarkUnread(chID discord.ChannelID, msgID discord.MessageID) error {
+	last := msgID - 1
+
+	if err := t.ack(chID, last, true); err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	t.marked[chID] = last
+	delete(t.pending, chID)
+	t.mutex.Unlock()
+
+	t.Call(&UpdateEvent{ChannelID: chID, Unread: true})
+	return nil
+}
+
+// Acked forgets that the channel was marked unread if a newer message was
+// acknowledged by another client.
+func (t *Tracker) Acked(chID discord.ChannelID, msgID discord.MessageID) {
+	t.mutex.Lock()
+	last, marked := t.marked[chID]
+	if marked && msgID > last {
+		delete(t.marked, chID)
+	}
+	t.mutex.Unlock()
+
+	if marked && msgID > last {
+		t.Call(&UpdateEvent{ChannelID: chID, Unread: false})
+	}
+}
+
+// LastRead returns the ID of the last read message in the channel.
+func (t *Tracker) LastRead(chID discord.ChannelID) (discord.MessageID, bool) {
+	t.mutex.Lock()
+	last, ok := t.marked[chID]
+	t.mutex.Unlock()
+
+	if ok {
+		return last, true
+	}
+
+	if rs := t.read.FindLast(chID); rs != nil {
+		return rs.LastMessageID, true
+	}
+
+	return 0, false
+}
+
+// Marked returns true if the user has marked the channel as unread and hasn't
+// read it since.
+func (t *Tracker) Marked(chID discord.ChannelID) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	_, ok := t.marked[chID]
+	return ok
+}
+
+// flush marks the messages that arrived while unfocused as read.
+func (t *Tracker) flush() {
+	t.mutex.Lock()
+	pending := t.pending
+	t.pending = map[discord.ChannelID]discord.MessageID{}
+	t.mutex.Unlock()
+
+	for chID, msgID := range pending {
+		t.MarkRead(chID, msgID)
+	}
+}
+
+func (t *Tracker) ack(chID discord.ChannelID, msgID discord.MessageID, manual bool) error {
+	var body struct {
+		Token  *string `json:"token"`
+		Manual bool    `json:"manual,omitempty"`
+	}
+	body.Manual = manual
+
+	return t.client.FastRequest(
+		"POST",
+		api.EndpointChannels+chID.String()+"/messages/"+msgID.String()+"/ack",
+		httputil.WithJSONBody(body),
+	)
+}
@@ -0,0 +1,113 @@
+package unread
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/arikawa/v2/utils/httputil"
+)
+
+type fakeReadState struct {
+	mutex sync.Mutex
+	read  map[discord.ChannelID]discord.MessageID
+}
+
+func (rs *fakeReadState) MarkRead(chID discord.ChannelID, msgID discord.MessageID) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	// The read state only moves forward.
+	if rs.read[chID] < msgID {
+		rs.read[chID] = msgID
+	}
+}
+
+func (rs *fakeReadState) FindLast(chID discord.ChannelID) *gateway.ReadState {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	id, ok := rs.read[chID]
+	if !ok {
+		return nil
+	}
+	return &gateway.ReadState{ChannelID: chID, LastMessageID: id}
+}
+
+type fakeClient struct {
+	mutex sync.Mutex
+	urls  []string
+}
+
+func (c *fakeClient) FastRequest(method, url string, opts ...httputil.RequestOption) error {
+	c.mutex.Lock()
+	c.urls = append(c.urls, url)
+	c.mutex.Unlock()
+	return nil
+}
+
+func TestMarkUnread(t *testing.T) {
+	rs := &fakeReadState{read: map[discord.ChannelID]discord.MessageID{1: 100}}
+	client := &fakeClient{}
+
+	tracker := New(rs, client)
+
+	var events []UpdateEvent
+	tracker.AddHandler(func(ev *UpdateEvent) { events = append(events, *ev) })
+
+	if err := tracker.MarkUnread(1, 90); err != nil {
+		t.Fatal("Failed to mark unread:", err)
+	}
+
+	if !strings.HasSuffix(client.urls[0], "/channels/1/messages/89/ack") {
+		t.Fatalf("Unexpected ack URL %q", client.urls[0])
+	}
+
+	if last, _ := tracker.LastRead(1); last != 89 {
+		t.Fatalf("Expected last read 89, got %d", last)
+	}
+
+	// Reading an older message doesn't undo marking unread.
+	tracker.MarkRead(1, 80)
+	if !tracker.Marked(1) {
+		t.Fatal("Channel should still be marked unread")
+	}
+
+	tracker.MarkRead(1, 100)
+	if tracker.Marked(1) {
+		t.Fatal("Channel should be read")
+	}
+
+	if len(events) != 2 || !events[0].Unread || events[1].Unread {
+		t.Fatalf("Unexpected events %v", events)
+	}
+}
+
+func TestFocus(t *testing.T) {
+	rs := &fakeReadState{read: map[discord.ChannelID]discord.MessageID{}}
+
+	tracker := New(rs, &fakeClient{})
+
+	tracker.SetFocused(false)
+
+	tracker.AutoMarkRead(1, 100)
+	if rs.FindLast(1) != nil {
+		t.Fatal("Message marked as read while unfocused")
+	}
+
+	// Other sessions have their own focus.
+	other := &fakeReadState{read: map[discord.ChannelID]discord.MessageID{}}
+	New(other, &fakeClient{}).AutoMarkRead(1, 100)
+
+	if other.FindLast(1) == nil {
+		t.Fatal("Unfocusing a session unfocused another one")
+	}
+
+	tracker.SetFocused(true)
+
+	if last := rs.FindLast(1); last == nil || last.LastMessageID != 100 {
+		t.Fatal("Message not marked as read after focusing")
+	}
+}
@@ -0,0 +1,100 @@
+package voice
+
+import (
+	"context"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-discord/internal/discord/state"
+	"github.com/diamondburned/cchat-discord/internal/segments/inline"
+	"github.com/diamondburned/cchat-discord/internal/segments/mention"
+	"github.com/diamondburned/cchat-discord/internal/segments/segutil"
+	"github.com/diamondburned/cchat/text"
+	"github.com/diamondburned/cchat/utils/empty"
+)
+
+// Participant is a non-messenger server that represents a user connected to a
+// voice channel.
+type Participant struct {
+	empty.Server
+	voice discord.VoiceState
+	user  *mention.User
+}
+
+var _ cchat.Server = (*Participant)(nil)
+
+func NewParticipant(s *state.Instance, vs discord.VoiceState) *Participant {
+	if vs.Member == nil {
+		vs.Member, _ = s.Cabinet.Member(vs.GuildID, vs.UserID)
+	}
+
+	var user *mention.User
+
+	if vs.Member != nil {
+		user = mention.NewUser(vs.Member.User)
+		user.WithMember(*vs.Member)
+	} else {
+		// We don't have the member yet, so request it for later.
+		s.MemberState.RequestMember(vs.GuildID, vs.UserID)
+		user = mention.NewUser(discord.User{ID: vs.UserID, Username: vs.UserID.String()})
+	}
+
+	user.WithGuildID(vs.GuildID)
+	user.WithState(s.State)
+	user.Prefetch()
+
+	return &Participant{
+		voice: vs,
+		user:  user,
+	}
+}
+
+func (p *Participant) ID() cchat.ID {
+	return p.voice.UserID.String()
+}
+
+func (p *Participant) Name() text.Rich {
+	var rich text.Rich
+
+	start, end := segutil.Write(&rich, p.user.DisplayName())
+	segutil.Add(&rich, mention.NewSegment(start, end, p.user))
+
+	if flags := p.flags(); len(flags) > 0 {
+		start, end := segutil.Write(&rich, " ("+strings.Join(flags, ", ")+")")
+		segutil.Add(&rich, inline.NewSegment(start, end, text.AttributeDimmed))
+	}
+
+	return rich
+}
+
+// flags returns the list of human-readable voice flags of the participant.
+func (p *Participant) flags() []string {
+	var flags []string
+
+	if p.voice.Deaf {
+		flags = append(flags, "server deafened")
+	}
+	if p.voice.SelfDeaf {
+		flags = append(flags, "deafened")
+	}
+	if p.voice.Mute {
+		flags = append(flags, "server muted")
+	}
+	if p.voice.SelfMute {
+		flags = append(flags, "muted")
+	}
+
+	if p.voice.SelfStream {
+		flags = append(flags, "live")
+	}
+
+	return flags
+}
+
+func (p *Participant) AsIconer() cchat.Iconer { return p }
+
+func (p *Participant) Icon(ctx context.Context, iconer cchat.IconContainer) (func(), error) {
+	iconer.SetIcon(p.user.Avatar())
+	return func() {}, nil
+}
@@ -0,0 +1,204 @@
+// Package voice provides read-only voice channel servers that list the users
+// connected to them.
+package voice
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-discord/internal/discord/state"
+	"github.com/diamondburned/cchat/text"
+	"github.com/diamondburned/cchat/utils/empty"
+)
+
+// GuildStageVoice is the channel type for stage channels. Arikawa does not
+// have this constant yet.
+const GuildStageVoice discord.ChannelType = 13
+
+// IsVoice returns true if the given channel type is a voice or stage channel.
+func IsVoice(chType discord.ChannelType) bool {
+	return chType == discord.GuildVoice || chType == GuildStageVoice
+}
+
+// Channel is a voice channel server. Its name has the number of participants,
+// which is kept updated in the container that the channel was listed in until
+// the channel is closed.
+type Channel struct {
+	empty.Server
+	id      discord.ChannelID
+	guildID discord.GuildID
+	state   *state.Instance
+	parent  cchat.ServersContainer
+	cancel  func()
+
+	mut sync.Mutex
+	// container is the last container given to Servers.
+	container cchat.ServersContainer
+	users     map[discord.UserID]struct{}
+}
+
+var _ cchat.Server = (*Channel)(nil)
+
+// New creates a new voice channel that is listed in the given container.
+func New(s *state.Instance, ch discord.Channel, parent cchat.ServersContainer) *Channel {
+	voice := &Channel{
+		id:      ch.ID,
+		guildID: ch.GuildID,
+		state:   s,
+		parent:  parent,
+	}
+
+	voice.users = voice.userIDs(voice.voiceStates())
+	voice.cancel = s.AddHandler(voice.onVoiceStateUpdate)
+
+	return voice
+}
+
+// Close unbinds the handler and forgets the container, so the channel is no
+// longer updated.
+func (ch *Channel) Close() {
+	ch.cancel()
+
+	ch.mut.Lock()
+	ch.container = nil
+	ch.mut.Unlock()
+}
+
+func (ch *Channel) ID() cchat.ID {
+	return ch.id.String()
+}
+
+func (ch *Channel) Name() text.Rich {
+	c, err := ch.state.Cabinet.Channel(ch.id)
+	if err != nil {
+		return text.Rich{Content: ch.id.String()}
+	}
+
+	var prefix = "🔊 "
+	if c.Type == GuildStageVoice {
+		prefix = "🎙 "
+	}
+
+	name := prefix + c.Name
+
+	if n := len(ch.voiceStates()); n > 0 {
+		name += " (" + strconv.Itoa(n) + ")"
+	}
+
+	return text.Plain(name)
+}
+
+func (ch *Channel) AsLister() cchat.Lister { return ch }
+
+// Servers lists all participants in the voice channel. The last given container
+// will be updated every time someone joins or leaves the channel.
+func (ch *Channel) Servers(container cchat.ServersContainer) error {
+	ch.mut.Lock()
+	ch.container = container
+	ch.mut.Unlock()
+
+	container.SetServers(ch.participants())
+	return nil
+}
+
+func (ch *Channel) onVoiceStateUpdate(ev *gateway.VoiceStateUpdateEvent) {
+	if ev.GuildID != ch.guildID {
+		return
+	}
+
+	ch.mut.Lock()
+
+	// A user leaving the channel will have a zero channel ID, so we have to
+	// check if they were previously in this channel.
+	_, wasHere := ch.users[ev.UserID]
+	if !wasHere && ev.ChannelID != ch.id {
+		ch.mut.Unlock()
+		return
+	}
+
+	ch.users = ch.userIDs(ch.voiceStates())
+	container := ch.container
+
+	ch.mut.Unlock()
+
+	// Update the participant count.
+	ch.parent.UpdateServer(replaceServer{ch})
+
+	if container != nil {
+		container.SetServers(ch.participants())
+	}
+}
+
+// userIDs returns the set of user IDs in the voice states.
+func (ch *Channel) userIDs(states []discord.VoiceState) map[discord.UserID]struct{} {
+	users := make(map[discord.UserID]struct{}, len(states))
+	for _, vs := range states {
+		users[vs.UserID] = struct{}{}
+	}
+	return users
+}
+
+// participants returns the list of participants sorted by their display name.
+func (ch *Channel) participants() []cchat.Server {
+	states := ch.voiceStates()
+
+	participants := make([]*Participant, len(states))
+	for i, vs := range states {
+		participants[i] = NewParticipant(ch.state, vs)
+	}
+
+	sort.SliceStable(participants, func(i, j int) bool {
+		return participants[i].user.DisplayName() < participants[j].user.DisplayName()
+	})
+
+	servers := make([]cchat.Server, len(participants))
+	for i, p := range participants {
+		servers[i] = p
+	}
+
+	return servers
+}
+
+// voiceStates returns the voice states of users connected to this channel.
+func (ch *Channel) voiceStates() []discord.VoiceState {
+	states, err := ch.state.Cabinet.VoiceStates(ch.guildID)
+	if err != nil {
+		return nil
+	}
+
+	filtered := states[:0]
+	for _, vs := range states {
+		if vs.ChannelID == ch.id {
+			filtered = append(filtered, vs)
+		}
+	}
+
+	return filtered
+}
+
+type replaceServer struct{ cchat.Server }
+
+func (rs replaceServer) PreviousID() (cchat.ID, bool) { return rs.Server.ID(), true }
+
+// Channels keeps the voice channels that were last listed together, so they can
+// be closed once they're listed again.
+type Channels struct {
+	mut sync.Mutex
+	chs []*Channel
+}
+
+// Replace closes the previously listed channels and keeps the given ones.
+func (c *Channels) Replace(chs []*Channel) {
+	c.mut.Lock()
+	old := c.chs
+	c.chs = chs
+	c.mut.Unlock()
+
+	for _, ch := range old {
+		ch.Close()
+	}
+}
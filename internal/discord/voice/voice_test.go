@@ -0,0 +1,124 @@
+package voice_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/cchat-discord/internal/discord/state/replay"
+	"github.com/diamondburned/cchat-discord/internal/discord/voice"
+)
+
+const timeout = 2 * time.Second
+
+func newHarness(t *testing.T) *replay.Harness {
+	t.Helper()
+
+	f, err := replay.Load("../state/replay/testdata/basic.json")
+	if err != nil {
+		t.Fatal("Failed to load fixture:", err)
+	}
+
+	h, err := replay.New(f)
+	if err != nil {
+		t.Fatal("Failed to create harness:", err)
+	}
+
+	t.Cleanup(h.Close)
+	return h
+}
+
+func TestChannelParticipants(t *testing.T) {
+	h := newHarness(t)
+
+	ch := discord.Channel{ID: 22, GuildID: 10, Type: discord.GuildVoice, Name: "voice"}
+
+	if err := h.Dispatch("CHANNEL_CREATE", ch); err != nil {
+		t.Fatal("Failed to dispatch:", err)
+	}
+
+	parent := &replay.ServersContainer{}
+	v := voice.New(h.Instance, ch, parent)
+	defer v.Close()
+
+	participants := &replay.ServersContainer{}
+	if err := v.Servers(participants); err != nil {
+		t.Fatal("Failed to list participants:", err)
+	}
+
+	err := h.Dispatch("VOICE_STATE_UPDATE", discord.VoiceState{
+		GuildID:   10,
+		ChannelID: 22,
+		UserID:    2,
+		SelfMute:  true,
+		SelfDeaf:  true,
+	})
+	if err != nil {
+		t.Fatal("Failed to dispatch:", err)
+	}
+
+	counted := parent.WaitFor(timeout, func() bool {
+		return len(parent.Updated) == 1 &&
+			parent.Updated[0].Name().Content == "🔊 voice (1)"
+	})
+	if !counted {
+		t.Fatal("Participant count was never updated")
+	}
+
+	listed := participants.WaitFor(timeout, func() bool {
+		return len(participants.Servers) == 1 &&
+			strings.HasSuffix(participants.Servers[0].Name().Content, "(deafened, muted)")
+	})
+	if !listed {
+		t.Fatal("Participant was never listed with both flags")
+	}
+
+	// Leaving the channel zeroes the channel ID.
+	err = h.Dispatch("VOICE_STATE_UPDATE", discord.VoiceState{GuildID: 10, UserID: 2})
+	if err != nil {
+		t.Fatal("Failed to dispatch:", err)
+	}
+
+	uncounted := parent.WaitFor(timeout, func() bool {
+		return len(parent.Updated) == 2 &&
+			parent.Updated[1].Name().Content == "🔊 voice"
+	})
+	if !uncounted {
+		t.Fatal("Participant count was never updated after leaving")
+	}
+
+	removed := participants.WaitFor(timeout, func() bool {
+		return len(participants.Servers) == 0
+	})
+	if !removed {
+		t.Fatal("Participant was never removed")
+	}
+}
+
+func TestChannelClose(t *testing.T) {
+	h := newHarness(t)
+
+	ch := discord.Channel{ID: 22, GuildID: 10, Type: discord.GuildVoice, Name: "voice"}
+
+	if err := h.Dispatch("CHANNEL_CREATE", ch); err != nil {
+		t.Fatal("Failed to dispatch:", err)
+	}
+
+	parent := &replay.ServersContainer{}
+	v := voice.New(h.Instance, ch, parent)
+	v.Close()
+
+	err := h.Dispatch("VOICE_STATE_UPDATE", discord.VoiceState{
+		GuildID:   10,
+		ChannelID: 22,
+		UserID:    2,
+	})
+	if err != nil {
+		t.Fatal("Failed to dispatch:", err)
+	}
+
+	if parent.WaitFor(timeout/10, func() bool { return len(parent.Updated) > 0 }) {
+		t.Fatal("Closed channel was still updated")
+	}
+}
@@ -37,6 +37,9 @@ func codeblock(r *renderer.Text, node ast.Node, enter bool) ast.WalkStatus {
 		seg.End = r.Buffer.Len()
 		r.Append(seg)
 
+		code := string(r.Buffer.Bytes()[seg.Start:seg.End])
+		highlight(r, seg.Start, code, seg.Language)
+
 		// Close the block.
 		r.Buffer.WriteString("\n---")
 		r.EndBlock()
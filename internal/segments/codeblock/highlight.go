@@ -0,0 +1,75 @@
+package codeblock
+
+import (
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/styles"
+	"github.com/diamondburned/cchat-discord/internal/segments/colored"
+	"github.com/diamondburned/cchat-discord/internal/segments/inline"
+	"github.com/diamondburned/cchat-discord/internal/segments/renderer"
+	"github.com/diamondburned/cchat/text"
+)
+
+// highlight adds colored and inline segments for the tokens of the code, which
+// starts at the given offset, if the renderer has a code style and the language
+// is known. Inline code has no language, so it's never highlighted.
+func highlight(r *renderer.Text, start int, code, language string) {
+	if r.CodeStyle == "" || language == "" {
+		return
+	}
+
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		return
+	}
+
+	// Don't let the lexer replace CRLFs, which would shift the offsets.
+	iterator, err := chroma.Coalesce(lexer).Tokenise(&chroma.TokeniseOptions{State: "root"}, code)
+	if err != nil {
+		return
+	}
+
+	style := styles.Get(r.CodeStyle)
+	end := start + len(code)
+
+	for _, token := range iterator.Tokens() {
+		tokenStart := start
+		start += len(token.Value)
+
+		// Lexers may add a trailing new line.
+		if start > end {
+			start = end
+		}
+
+		if strings.TrimSpace(token.Value) == "" || tokenStart >= start {
+			continue
+		}
+
+		entry := style.Get(token.Type)
+
+		if entry.Colour.IsSet() {
+			rgb := uint32(entry.Colour.Red())<<16 |
+				uint32(entry.Colour.Green())<<8 |
+				uint32(entry.Colour.Blue())
+
+			r.Append(colored.NewSegment(tokenStart, start, rgb))
+		}
+
+		var attr text.Attribute
+		if entry.Bold == chroma.Yes {
+			attr |= text.AttributeBold
+		}
+		if entry.Italic == chroma.Yes {
+			attr |= text.AttributeItalics
+		}
+		if entry.Underline == chroma.Yes {
+			attr |= text.AttributeUnderline
+		}
+
+		if attr != 0 {
+			r.Append(inline.NewSegment(tokenStart, start, attr))
+		}
+	}
+}
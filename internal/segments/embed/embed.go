@@ -1,7 +1,6 @@
 package embed
 
 import (
-	"fmt"
 	"time"
 
 	"github.com/diamondburned/arikawa/v2/discord"
@@ -10,6 +9,7 @@ import (
 	"github.com/diamondburned/cchat-discord/internal/segments/inline"
 	"github.com/diamondburned/cchat-discord/internal/segments/link"
 	"github.com/diamondburned/cchat-discord/internal/segments/renderer"
+	"github.com/diamondburned/cchat-discord/internal/segments/spoiler"
 	"github.com/diamondburned/cchat-discord/internal/urlutils"
 	"github.com/diamondburned/cchat/text"
 	"github.com/diamondburned/ningen/v2/md"
@@ -90,11 +90,7 @@ func RenderEmbed(r *renderer.Text, embed discord.Embed, m *discord.Message, s st
 	if len(embed.Fields) > 0 {
 		// Pad two new lines.
 		r.StartBlockN(2)
-
-		// Write fields indented once.
-		for _, field := range embed.Fields {
-			fmt.Fprintf(r.Buffer, "\t%s: %s\n", field.Name, field.Value)
-		}
+		RenderFields(r, embed.Fields, m, s)
 	}
 
 	if f := embed.Footer; f != nil && f.Text != "" {
@@ -145,6 +141,18 @@ func RenderAttachments(r *renderer.Text, attachments []discord.Attachment) {
 }
 
 func RenderAttachment(r *renderer.Text, a discord.Attachment) {
+	// Spoiler attachments are never shown inline, since images can't be hidden
+	// until revealed.
+	if spoiler.IsAttachment(a) {
+		start, end := r.WriteStringf(
+			"Spoiler: %s (%s)",
+			spoiler.AttachmentName(a), humanize.Bytes(a.Size),
+		)
+
+		r.Append(link.NewSegment(start, end, a.URL), spoiler.NewSegment(start, end))
+		return
+	}
+
 	if urlutils.ExtIs(a.Proxy, imageExts) {
 		r.Append(Attachment(r.Buffer.Len(), a))
 		return
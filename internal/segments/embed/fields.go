@@ -0,0 +1,184 @@
+package embed
+
+import (
+	"strings"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/state/store"
+	"github.com/diamondburned/cchat-discord/internal/segments/inline"
+	"github.com/diamondburned/cchat-discord/internal/segments/renderer"
+	"github.com/diamondburned/cchat/text"
+	"github.com/diamondburned/ningen/v2/md"
+	"github.com/mattn/go-runewidth"
+)
+
+// maxInlineFields is the maximum number of inline fields in a row, which is
+// the same as the official client's.
+const maxInlineFields = 3
+
+// columnGap is the padding between the columns of inline fields.
+const columnGap = "   "
+
+// RenderFields renders the embed fields. Consecutive inline fields are grouped
+// into rows of columns; other fields take up their own lines.
+func RenderFields(r *renderer.Text, fields []discord.EmbedField, m *discord.Message, s store.Cabinet) {
+	for len(fields) > 0 {
+		var n int
+		for n < len(fields) && n < maxInlineFields && fields[n].Inline {
+			n++
+		}
+
+		if n < 2 {
+			renderField(r, fields[0], m, s)
+			fields = fields[1:]
+			continue
+		}
+
+		renderInlineFields(r, fields[:n], m, s)
+		fields = fields[n:]
+	}
+}
+
+// renderField renders the field name in bold above its value.
+func renderField(r *renderer.Text, field discord.EmbedField, m *discord.Message, s store.Cabinet) {
+	r.EnsureBreak()
+
+	if field.Name != "" {
+		start, end := r.WriteString(field.Name)
+		r.Append(inline.NewSegment(start, end, text.AttributeBold))
+		r.EnsureBreak()
+	}
+
+	if field.Value != "" {
+		value := r.Clone([]byte(field.Value))
+		value.Walk(md.ParseWithMessage(value.Source, s, m, false))
+		r.Join(value)
+	}
+
+	r.EnsureBreak()
+}
+
+// renderInlineFields renders the fields as columns of a single row, with the
+// names on the first line and the values below. Columns are aligned by the
+// display width of their lines, so wide characters take up two cells.
+func renderInlineFields(r *renderer.Text, fields []discord.EmbedField, m *discord.Message, s store.Cabinet) {
+	cells := make([]cell, len(fields))
+	widths := make([]int, len(fields))
+	height := 0
+
+	for i, field := range fields {
+		cells[i] = renderCell(r, field, m, s)
+
+		for _, line := range cells[i].lines {
+			if w := runewidth.StringWidth(line); w > widths[i] {
+				widths[i] = w
+			}
+		}
+
+		if len(cells[i].lines) > height {
+			height = len(cells[i].lines)
+		}
+	}
+
+	r.EnsureBreak()
+
+	for line := 0; line < height; line++ {
+		for i, cell := range cells {
+			var content string
+			if line < len(cell.lines) {
+				content = cell.lines[line]
+				cell.writeLine(r, line)
+			}
+
+			// Don't pad the last column.
+			if i < len(cells)-1 {
+				pad := widths[i] - runewidth.StringWidth(content)
+				r.Buffer.WriteString(strings.Repeat(" ", pad) + columnGap)
+			}
+		}
+
+		r.Buffer.WriteByte('\n')
+	}
+}
+
+// cell is a rendered inline field.
+type cell struct {
+	lines []string
+	// starts has the offsets of the lines in the rendered field.
+	starts   []int
+	segments []text.Segment
+}
+
+// renderCell renders the field name and value on their own, with the parent
+// renderer's code style.
+func renderCell(parent *renderer.Text, field discord.EmbedField, m *discord.Message, s store.Cabinet) cell {
+	r := renderer.New([]byte(field.Value))
+	r.CodeStyle = parent.CodeStyle
+	r.WithState(m, s)
+
+	start, end := r.WriteString(field.Name)
+	r.Append(inline.NewSegment(start, end, text.AttributeBold))
+	r.Buffer.WriteByte('\n')
+
+	r.Walk(md.ParseWithMessage(r.Source, s, m, false))
+
+	content := r.String()
+	lines := strings.Split(content, "\n")
+	starts := make([]int, len(lines))
+
+	var offset int
+	for i, line := range lines {
+		starts[i] = offset
+		offset += len(line) + 1
+	}
+
+	return cell{
+		lines:    lines,
+		starts:   starts,
+		segments: r.Segments,
+	}
+}
+
+// writeLine writes the line of the cell, moving the segments within it to
+// where it is written. Segments spanning several lines are split.
+func (c cell) writeLine(r *renderer.Text, line int) {
+	pos := r.Buffer.Len()
+	r.Buffer.WriteString(c.lines[line])
+
+	start := c.starts[line]
+	end := start + len(c.lines[line])
+
+	for _, seg := range c.segments {
+		i, j := seg.Bounds()
+
+		// Segments without a length, such as images, stay on the line they
+		// start in.
+		if i == j {
+			if start <= i && i <= end {
+				r.Append(offsetSegment{seg, pos + i - start, pos + i - start})
+			}
+			continue
+		}
+
+		if i < start {
+			i = start
+		}
+		if j > end {
+			j = end
+		}
+
+		if i < j {
+			r.Append(offsetSegment{seg, pos + i - start, pos + j - start})
+		}
+	}
+}
+
+// offsetSegment is a segment that was moved elsewhere in the text.
+type offsetSegment struct {
+	text.Segment
+	start, end int
+}
+
+func (seg offsetSegment) Bounds() (int, int) {
+	return seg.start, seg.end
+}
@@ -0,0 +1,112 @@
+// Package heading renders headers, such as "# Title", and subtext, such as
+// "-# small text", which take up their own lines.
+package heading
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/diamondburned/cchat-discord/internal/segments/inline"
+	"github.com/diamondburned/cchat-discord/internal/segments/renderer"
+	"github.com/diamondburned/cchat/text"
+	"github.com/yuin/goldmark/ast"
+)
+
+func init() {
+	renderer.Register(KindHeading, heading)
+	renderer.Register(KindSubtext, subtext)
+	renderer.RegisterTransformer(Transform)
+}
+
+var (
+	headingRegex = regexp.MustCompile(`^(#{1,3}) +`)
+	subtextRegex = regexp.MustCompile(`^-# +`)
+)
+
+type Heading struct {
+	ast.BaseBlock
+	Level int
+}
+
+var KindHeading = ast.NewNodeKind("Heading")
+
+// Kind implements Node.Kind.
+func (h *Heading) Kind() ast.NodeKind {
+	return KindHeading
+}
+
+// Dump implements Node.Dump.
+func (h *Heading) Dump(source []byte, level int) {
+	ast.DumpHelper(h, source, level, map[string]string{
+		"Level": strconv.Itoa(h.Level),
+	}, nil)
+}
+
+type Subtext struct {
+	ast.BaseBlock
+}
+
+var KindSubtext = ast.NewNodeKind("Subtext")
+
+// Kind implements Node.Kind.
+func (s *Subtext) Kind() ast.NodeKind {
+	return KindSubtext
+}
+
+// Dump implements Node.Dump.
+func (s *Subtext) Dump(source []byte, level int) {
+	ast.DumpHelper(s, source, level, nil, nil)
+}
+
+// Transform wraps the lines starting with a header or subtext prefix.
+func Transform(doc ast.Node, src []byte) {
+	for _, line := range renderer.ParagraphLines(doc, src) {
+		if m := line.MatchPrefix(headingRegex); m != nil {
+			line.Wrap(&Heading{Level: len(m[1])})
+			continue
+		}
+
+		if m := line.MatchPrefix(subtextRegex); m != nil {
+			line.Wrap(&Subtext{})
+		}
+	}
+}
+
+// levelAttrs maps the header levels to their styles. Headers can't be sized,
+// so the smallest ones are dimmed instead.
+var levelAttrs = [...]text.Attribute{
+	1: text.AttributeBold,
+	2: text.AttributeBold,
+	3: text.AttributeBold | text.AttributeDimmed,
+}
+
+func heading(r *renderer.Text, node ast.Node, enter bool) ast.WalkStatus {
+	if enter {
+		renderLine(r, node, levelAttrs[node.(*Heading).Level])
+	}
+	return ast.WalkSkipChildren
+}
+
+func subtext(r *renderer.Text, node ast.Node, enter bool) ast.WalkStatus {
+	if enter {
+		renderLine(r, node, text.AttributeDimmed)
+	}
+	return ast.WalkSkipChildren
+}
+
+// renderLine renders the line, then styles it without its line break.
+func renderLine(r *renderer.Text, n ast.Node, attr text.Attribute) {
+	start := r.Buffer.Len()
+	r.RenderChildren(n)
+
+	end := r.Buffer.Len()
+	for end > start && r.Buffer.Bytes()[end-1] == '\n' {
+		end--
+	}
+
+	if start < end {
+		r.Append(inline.NewSegment(start, end, attr))
+	}
+
+	r.EnsureBreak()
+}
@@ -2,6 +2,7 @@ package inline
 
 import (
 	"github.com/diamondburned/cchat-discord/internal/segments/renderer"
+	"github.com/diamondburned/cchat-discord/internal/segments/spoiler"
 	"github.com/diamondburned/cchat/text"
 	"github.com/diamondburned/cchat/utils/empty"
 	"github.com/diamondburned/ningen/v2/md"
@@ -16,6 +17,13 @@ func init() {
 // new segment for overlapping attributes.
 func inline(r *renderer.Text, node ast.Node, enter bool) ast.WalkStatus {
 	n := node.(*md.Inline)
+
+	// Spoilers have their own segments.
+	if n.Attr == md.AttrSpoiler {
+		spoiler.Render(r, enter)
+		return ast.WalkContinue
+	}
+
 	// For instructions on how this works, refer to inline_attr.jpg.
 
 	// Pop the last segment if it's not empty.
@@ -107,7 +115,7 @@ func NewSegmentFromMD(start, end int, attr md.Attribute) Segment {
 		seg.attributes |= Attribute(text.AttributeStrikethrough)
 	}
 	if attr.Has(md.AttrSpoiler) {
-		seg.attributes |= Attribute(text.AttributeSpoiler)
+		seg.attributes |= Attribute(spoiler.Attributes)
 	}
 	if attr.Has(md.AttrMonospace) {
 		seg.attributes |= Attribute(text.AttributeMonospace)
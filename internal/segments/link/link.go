@@ -1,15 +1,21 @@
 package link
 
 import (
+	"bytes"
+
 	"github.com/diamondburned/cchat-discord/internal/segments/renderer"
+	"github.com/diamondburned/cchat-discord/internal/segments/spoiler"
 	"github.com/diamondburned/cchat/text"
 	"github.com/diamondburned/cchat/utils/empty"
+	"github.com/diamondburned/ningen/v2/md"
 	"github.com/yuin/goldmark/ast"
 )
 
 func init() {
 	renderer.Register(ast.KindLink, link)
 	renderer.Register(ast.KindAutoLink, autoLink)
+	renderer.Register(ast.KindImage, image)
+	renderer.RegisterTransformer(Transform)
 }
 
 func link(r *renderer.Text, node ast.Node, enter bool) ast.WalkStatus {
@@ -37,17 +43,137 @@ func link(r *renderer.Text, node ast.Node, enter bool) ast.WalkStatus {
 	return ast.WalkContinue
 }
 
+// image renders an image as a link to it, since images can't be embedded in
+// the text. The URL is used if the image has no alternative text.
+func image(r *renderer.Text, node ast.Node, enter bool) ast.WalkStatus {
+	n := node.(*ast.Image)
+
+	if !enter {
+		return ast.WalkContinue
+	}
+
+	start := r.Buffer.Len()
+	r.RenderChildren(n)
+
+	if r.Buffer.Len() == start {
+		r.Buffer.Write(n.Destination)
+	}
+
+	r.Append(NewSegment(start, r.Buffer.Len(), string(n.Destination)))
+
+	return ast.WalkSkipChildren
+}
+
 func autoLink(r *renderer.Text, node ast.Node, enter bool) ast.WalkStatus {
 	n := node.(*ast.AutoLink)
 
-	if enter {
-		start, end := r.Write(n.URL(r.Source))
-		r.Append(NewSegment(start, end, string(n.URL(r.Source))))
+	if !enter {
+		return ast.WalkContinue
+	}
+
+	url := n.URL(r.Source)
+
+	// The closing delimiter of a spoiler taken by the autolink is only written
+	// back if the spoiler wasn't opened, since Transform moves opened ones
+	// into a spoiler node.
+	closed := bytes.HasSuffix(url, []byte(spoiler.Delimiter))
+	if closed {
+		url = url[:len(url)-len(spoiler.Delimiter)]
+	}
+
+	start, end := r.Write(url)
+	r.Append(NewSegment(start, end, string(url)))
+
+	if closed && !inSpoiler(n) {
+		r.Buffer.WriteString(spoiler.Delimiter)
 	}
 
 	return ast.WalkContinue
 }
 
+// Transform fixes spoilers that end with an autolink. Autolinks only end at a
+// space, so they take the closing delimiter of a spoiler, which leaves the
+// opening one as text. The nodes between the opening delimiter and the
+// autolink are moved into a spoiler node.
+func Transform(doc ast.Node, src []byte) {
+	var links []*ast.AutoLink
+
+	ast.Walk(doc, func(n ast.Node, enter bool) (ast.WalkStatus, error) {
+		if l, ok := n.(*ast.AutoLink); ok && enter {
+			if bytes.HasSuffix(l.URL(src), []byte(spoiler.Delimiter)) {
+				links = append(links, l)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, l := range links {
+		wrapSpoiler(l, src)
+	}
+}
+
+// wrapSpoiler finds the opening delimiter in the text before the autolink,
+// then moves everything after it up to the autolink into a spoiler node.
+func wrapSpoiler(l *ast.AutoLink, src []byte) {
+	var open *ast.Text
+	var i int
+
+	for n := l.PreviousSibling(); n != nil && open == nil; n = n.PreviousSibling() {
+		t, ok := n.(*ast.Text)
+		if !ok {
+			continue
+		}
+
+		if i = bytes.LastIndex(t.Segment.Value(src), []byte(spoiler.Delimiter)); i > -1 {
+			open = t
+		}
+	}
+
+	if open == nil {
+		return
+	}
+
+	parent := l.Parent()
+	node := &md.Inline{Attr: md.AttrSpoiler}
+
+	// Split the text after the delimiter off into the spoiler.
+	stop := open.Segment.Start + i
+	if rest := open.Segment.WithStart(stop + len(spoiler.Delimiter)); rest.Len() > 0 {
+		t := ast.NewTextSegment(rest)
+		t.SetSoftLineBreak(open.SoftLineBreak())
+		t.SetHardLineBreak(open.HardLineBreak())
+		node.AppendChild(node, t)
+
+		open.SetSoftLineBreak(false)
+		open.SetHardLineBreak(false)
+	}
+	open.Segment = open.Segment.WithStop(stop)
+
+	for n := open.NextSibling(); n != nil; {
+		next := n.NextSibling()
+
+		parent.RemoveChild(parent, n)
+		node.AppendChild(node, n)
+
+		if n == l {
+			break
+		}
+		n = next
+	}
+
+	parent.InsertAfter(parent, open, node)
+}
+
+// inSpoiler returns true if the node is in a spoiler.
+func inSpoiler(n ast.Node) bool {
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		if i, ok := p.(*md.Inline); ok && i.Attr.Has(md.AttrSpoiler) {
+			return true
+		}
+	}
+	return false
+}
+
 type URL string
 
 var _ text.Linker = (*URL)(nil)
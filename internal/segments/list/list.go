@@ -0,0 +1,123 @@
+// Package list renders bulleted lists, such as "- item", and numbered lists,
+// such as "1. item". Items are indented by their nesting level.
+package list
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/diamondburned/cchat-discord/internal/segments/renderer"
+	"github.com/yuin/goldmark/ast"
+)
+
+func init() {
+	renderer.Register(KindItem, item)
+	renderer.RegisterTransformer(Transform)
+}
+
+var (
+	bulletRegex   = regexp.MustCompile(`^( *)[-*] +`)
+	numberedRegex = regexp.MustCompile(`^( *)(\d{1,9})\. +`)
+)
+
+// Bullet is the marker of bulleted items.
+const Bullet = "•"
+
+type Item struct {
+	ast.BaseBlock
+	Level int
+	// Number is the number of the item if it's numbered.
+	Number   int
+	Numbered bool
+}
+
+var KindItem = ast.NewNodeKind("ListItem")
+
+// Kind implements Node.Kind.
+func (i *Item) Kind() ast.NodeKind {
+	return KindItem
+}
+
+// Dump implements Node.Dump.
+func (i *Item) Dump(source []byte, level int) {
+	ast.DumpHelper(i, source, level, map[string]string{
+		"Level":  strconv.Itoa(i.Level),
+		"Number": strconv.Itoa(i.Number),
+	}, nil)
+}
+
+// Transform wraps the lines starting with a list marker. Numbered items that
+// follow one another at the same level are numbered from the first one, like
+// the official client does.
+func Transform(doc ast.Node, src []byte) {
+	var last *Item
+	var lastParent ast.Node
+
+	for _, line := range renderer.ParagraphLines(doc, src) {
+		var item *Item
+
+		if m := line.MatchPrefix(numberedRegex); m != nil {
+			item = &Item{Level: level(m[1]), Numbered: true}
+			item.Number, _ = strconv.Atoi(string(m[2]))
+
+			if last != nil && lastParent == line.Parent && last.Numbered &&
+				last.Level == item.Level && last.NextSibling() == line.Nodes[0] {
+
+				item.Number = last.Number + 1
+			}
+		} else if m := line.MatchPrefix(bulletRegex); m != nil {
+			item = &Item{Level: level(m[1])}
+		}
+
+		if item != nil {
+			line.Wrap(item)
+		}
+
+		last = item
+		lastParent = line.Parent
+	}
+}
+
+// level returns the nesting level from the indentation of two spaces per
+// level.
+func level(indent []byte) int {
+	return len(indent) / 2
+}
+
+func item(r *renderer.Text, node ast.Node, enter bool) ast.WalkStatus {
+	n := node.(*Item)
+
+	if !enter {
+		return ast.WalkSkipChildren
+	}
+
+	// Items always start on their own line. The first line of a quoted
+	// paragraph already starts after the quote prefix, which has to be kept.
+	if startsQuotedLine(n) {
+		r.StartIndentedBlockN(0, n.Level)
+	} else {
+		r.StartIndentedBlockN(1, n.Level)
+	}
+
+	if n.Numbered {
+		r.Buffer.WriteString(strconv.Itoa(n.Number) + ". ")
+	} else {
+		r.Buffer.WriteString(Bullet + " ")
+	}
+
+	r.RenderChildren(n)
+	r.EnsureBreak()
+
+	return ast.WalkSkipChildren
+}
+
+// startsQuotedLine returns true if the item is the first line of a paragraph in
+// a blockquote.
+func startsQuotedLine(n ast.Node) bool {
+	para := n.Parent()
+	if para == nil || para.FirstChild() != n || para.Parent() == nil {
+		return false
+	}
+
+	return para.Parent().Kind() == ast.KindBlockquote
+}
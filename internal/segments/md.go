@@ -4,6 +4,7 @@ import (
 	"github.com/diamondburned/arikawa/v2/discord"
 	"github.com/diamondburned/arikawa/v2/state/store"
 	"github.com/diamondburned/cchat-discord/internal/segments/embed"
+	"github.com/diamondburned/cchat-discord/internal/segments/reaction"
 	"github.com/diamondburned/cchat-discord/internal/segments/renderer"
 	"github.com/diamondburned/cchat/text"
 	"github.com/diamondburned/ningen/v2/md"
@@ -12,18 +13,24 @@ import (
 	_ "github.com/diamondburned/cchat-discord/internal/segments/codeblock"
 	_ "github.com/diamondburned/cchat-discord/internal/segments/colored"
 	_ "github.com/diamondburned/cchat-discord/internal/segments/emoji"
+	_ "github.com/diamondburned/cchat-discord/internal/segments/heading"
 	_ "github.com/diamondburned/cchat-discord/internal/segments/inline"
 	_ "github.com/diamondburned/cchat-discord/internal/segments/link"
+	_ "github.com/diamondburned/cchat-discord/internal/segments/list"
 	_ "github.com/diamondburned/cchat-discord/internal/segments/mention"
+	_ "github.com/diamondburned/cchat-discord/internal/segments/timestamp"
 )
 
+// ParseMessage renders the message without syntax highlighting.
 func ParseMessage(m *discord.Message, s store.Cabinet) text.Rich {
 	var rich text.Rich
-	ParseMessageRich(&rich, m, s)
+	ParseMessageRich(&rich, m, s, "")
 	return rich
 }
 
-func ParseMessageRich(rich *text.Rich, m *discord.Message, s store.Cabinet) {
+// ParseMessageRich renders the message and appends it to rich. Code blocks are
+// highlighted with the given style if it's not empty.
+func ParseMessageRich(rich *text.Rich, m *discord.Message, s store.Cabinet, codeStyle string) {
 	content := []byte(m.Content)
 
 	r := renderer.New(content)
@@ -32,39 +39,49 @@ func ParseMessageRich(rich *text.Rich, m *discord.Message, s store.Cabinet) {
 
 	// Register the needed states for some renderers.
 	r.WithState(m, s)
+	r.CodeStyle = codeStyle
 
 	// Render the main message body.
 	if len(content) > 0 {
-		node := md.ParseWithMessage(content, s, m, true)
+		// Messages may have masked links too, so parse links like in embeds.
+		// Images are only rendered as links.
+		node := md.ParseWithMessage(content, s, m, false)
 		r.Walk(node)
 	}
 
 	// Render the extra bits.
 	embed.RenderAttachments(r, m.Attachments)
 	embed.RenderEmbeds(r, m.Embeds, m, s)
+	reaction.RenderReactions(r, m.Reactions)
 
 	rich.Content = r.String()
 	rich.Segments = append(rich.Segments, r.Segments...)
 }
 
+// ParseWithMessage renders the given content without syntax highlighting.
 func ParseWithMessage(b []byte, m *discord.Message, s store.Cabinet) text.Rich {
 	var rich text.Rich
-	ParseWithMessageRich(&rich, b, m, s)
+	ParseWithMessageRich(&rich, b, m, s, "")
 	return rich
 }
 
-func ParseWithMessageRich(rich *text.Rich, b []byte, m *discord.Message, s store.Cabinet) {
+// ParseWithMessageRich renders the given content and appends it to rich. Code
+// blocks are highlighted with the given style if it's not empty.
+func ParseWithMessageRich(
+	rich *text.Rich, b []byte, m *discord.Message, s store.Cabinet, codeStyle string) {
+
 	if len(b) == 0 {
 		return
 	}
 
-	node := md.ParseWithMessage(b, s, m, true)
+	node := md.ParseWithMessage(b, s, m, false)
 
 	r := renderer.New(b)
 	r.Buffer.Grow(len(rich.Content))
 	r.Buffer.WriteString(rich.Content)
 
 	r.WithState(m, s)
+	r.CodeStyle = codeStyle
 	r.Walk(node)
 
 	rich.Content = r.String()
@@ -3,10 +3,18 @@ package segments
 import (
 	"errors"
 	"log"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/diamondburned/arikawa/v2/discord"
-	"github.com/diamondburned/arikawa/v2/state"
+	"github.com/diamondburned/arikawa/v2/state/store"
+	"github.com/diamondburned/cchat-discord/internal/segments/blockquote"
+	"github.com/diamondburned/cchat-discord/internal/segments/codeblock"
+	"github.com/diamondburned/cchat-discord/internal/segments/emoji"
+	"github.com/diamondburned/cchat-discord/internal/segments/inline"
+	"github.com/diamondburned/cchat-discord/internal/segments/link"
+	"github.com/diamondburned/cchat-discord/internal/segments/spoiler"
 	"github.com/diamondburned/cchat/text"
 	"github.com/go-test/deep"
 )
@@ -27,43 +35,113 @@ func init() {
 	deep.CompareUnexportedFields = true
 }
 
+func parse(b []byte) text.Rich {
+	return ParseWithMessage(b, &discord.Message{}, store.NoopCabinet)
+}
+
 func TestParse(t *testing.T) {
 	var tests = []segtest{
 		mksegtest(
 			"This makes me <:Thonk:456835728559702052>",
 			"This makes me ",
-			EmojiSegment{
-				start:    14,
-				large:    false,
-				name:     "Thonk",
-				emojiURL: "https://cdn.discordapp.com/emojis/456835728559702052.png?v=1&size=64",
+			emoji.Segment{
+				Start: 14,
+				Emoji: emoji.Emoji{
+					Name:     "Thonk",
+					Large:    false,
+					EmojiURL: "https://cdn.discordapp.com/emojis/456835728559702052.png?size=64&v=1",
+				},
 			},
 		),
 		mksegtest(
 			"This is https://google.com",
 			"This is https://google.com",
-			LinkSegment{8, 26, "https://google.com"},
+			link.NewSegment(8, 26, "https://google.com"),
 		),
 		mksegtest(
 			"**bold and *italics*** text",
 			"bold and italics text",
-			InlineSegment{0, 9, text.AttrBold},
-			InlineSegment{9, 16, text.AttrBold | text.AttrItalics},
+			inline.NewSegment(0, 9, text.AttributeBold),
+			inline.NewSegment(9, 16, text.AttributeBold, text.AttributeItalics),
 		),
 		mksegtest(
 			"> imagine best trap\n> not being astolfo",
 			"> imagine best trap\n> not being astolfo",
-			BlockquoteSegment{0, 39},
+			blockquote.Segment{Start: 0, End: 39},
 		),
 		mksegtest(
 			"```go\npackage main\n\nfunc main() {}```",
-			"package main\n\nfunc main() {}",
-			CodeblockSegment{0, 28, "go"},
+			"---\npackage main\n\nfunc main() {}\n---",
+			codeblock.CodeblockSegment{Start: 4, End: 32, Language: "go"},
+		),
+		mksegtest(
+			"a ||secret `code`|| b",
+			"a secret code b",
+			inline.NewSegment(9, 13, text.AttributeMonospace),
+			spoiler.NewSegment(2, 13),
+		),
+		mksegtest(
+			"||https://google.com||",
+			"https://google.com",
+			link.NewSegment(0, 18, "https://google.com"),
+			spoiler.NewSegment(0, 18),
+		),
+		mksegtest(
+			"hello ||spoiler https://a.b/c|| end",
+			"hello spoiler https://a.b/c end",
+			link.NewSegment(14, 27, "https://a.b/c"),
+			spoiler.NewSegment(6, 27),
+		),
+		mksegtest(
+			"no spoiler https://a.b/c||",
+			"no spoiler https://a.b/c||",
+			link.NewSegment(11, 24, "https://a.b/c"),
+		),
+		mksegtest(
+			"# Header\ntext\n-# subtext",
+			"Header\ntext\nsubtext",
+			inline.NewSegment(0, 6, text.AttributeBold),
+			inline.NewSegment(12, 19, text.AttributeDimmed),
+		),
+		mksegtest(
+			"## Section\n### Small",
+			"Section\nSmall",
+			inline.NewSegment(0, 7, text.AttributeBold),
+			inline.NewSegment(8, 13, text.AttributeBold|text.AttributeDimmed),
+		),
+		mksegtest(
+			"- one\n  - nested\n1. first\n1. second",
+			"• one\n    • nested\n1. first\n2. second",
+		),
+		mksegtest(
+			"> # head\n> - a\n>   - b",
+			"> head\n> • a\n>     • b",
+			inline.NewSegment(2, 6, text.AttributeBold),
+			blockquote.Segment{Start: 0, End: 26},
+		),
+		mksegtest(
+			"text - not a list\n    - deep",
+			"text - not a list\n        • deep",
+		),
+		mksegtest(
+			"#hashtag\n-dash",
+			"#hashtag\n-dash",
+		),
+		mksegtest(
+			"see [the docs](https://example.com)",
+			"see the docs",
+			link.NewSegment(4, 12, "https://example.com"),
+		),
+		mksegtest(
+			"![cat](https://example.com/cat.png) ![](https://example.com/a.png)",
+			"cat https://example.com/a.png",
+			link.NewSegment(0, 3, "https://example.com/cat.png"),
+			link.NewSegment(4, 29, "https://example.com/a.png"),
 		),
 	}
 
 	for _, test := range tests {
-		text := Parse([]byte(test.in))
+		text := parse([]byte(test.in))
 		log.Printf("Output: %#v\n", text)
 
 		assert(t, text, test)
@@ -82,24 +160,237 @@ func TestMessage(t *testing.T) {
 		}},
 	}
 
-	var store = mockStore{}
+	var cabinet = store.NoopCabinet
+	cabinet.ChannelStore = mockStore{}
 
-	text := ParseMessage(&msg, store)
+	text := ParseMessage(&msg, cabinet)
 	log.Printf("Output: %#v\n", text)
 
-	assert(t, text, mksegtest(
-		"Message",
-		"@astolfo where's #traps",
-		MentionSegment{0, 8},
-		MentionSegment{17, 23},
-	))
+	const expect = "@astolfo where's #traps"
+	if text.Content != expect {
+		t.Fatalf("Expected %q, got %q", expect, text.Content)
+	}
+
+	var bounds = [][2]int{{0, 8}, {17, 23}}
+	if len(text.Segments) != len(bounds) {
+		t.Fatalf("Expected %d segments, got %d", len(bounds), len(text.Segments))
+	}
+
+	for i, seg := range text.Segments {
+		if seg.AsMentioner() == nil {
+			t.Errorf("Segment %d is not a mention", i)
+		}
+
+		if start, end := seg.Bounds(); start != bounds[i][0] || end != bounds[i][1] {
+			t.Errorf("Segment %d: expected bounds %v, got [%d %d]", i, bounds[i], start, end)
+		}
+	}
+}
+
+func TestSpoilerAttachment(t *testing.T) {
+	var msg = discord.Message{
+		Attachments: []discord.Attachment{{
+			Filename: "SPOILER_cat.png",
+			Size:     2048,
+			URL:      "https://cdn.discordapp.com/SPOILER_cat.png",
+			Proxy:    "https://media.discordapp.net/SPOILER_cat.png",
+		}},
+	}
+
+	text := ParseMessage(&msg, store.NoopCabinet)
+
+	const expect = "Spoiler: cat.png (2.0 kB)"
+	if text.Content != expect {
+		t.Fatalf("Expected %q, got %q", expect, text.Content)
+	}
+
+	for _, seg := range text.Segments {
+		if seg.AsImager() != nil {
+			t.Fatal("Spoiler attachment shown as an image")
+		}
+	}
+
+	if len(text.Segments) != 2 || text.Segments[1].AsAttributor() == nil {
+		t.Fatalf("Unexpected segments %#v", text.Segments)
+	}
+}
+
+func TestTimestamps(t *testing.T) {
+	local := time.Local
+	time.Local = time.UTC
+	defer func() { time.Local = local }()
+
+	var tests = map[string]string{
+		"<t:1600000000>":          "September 13, 2020 12:26 PM",
+		"<t:1600000000:t>":        "12:26 PM",
+		"<t:1600000000:T>":        "12:26:40 PM",
+		"<t:1600000000:d>":        "09/13/2020",
+		"<t:1600000000:D>":        "September 13, 2020",
+		"<t:1600000000:F>":        "Sunday, September 13, 2020 12:26 PM",
+		"at **<t:1600000000:t>**": "at 12:26 PM",
+		"`<t:1600000000:t>`":      "<t:1600000000:t>",
+	}
+
+	for in, expect := range tests {
+		if got := parse([]byte(in)).Content; got != expect {
+			t.Errorf("%q: expected %q, got %q", in, expect, got)
+		}
+	}
+
+	rich := parse([]byte("ends <t:1600000000:R>!"))
+	if !strings.HasSuffix(rich.Content, " ago!") {
+		t.Errorf("Unexpected relative timestamp %q", rich.Content)
+	}
+
+	if len(rich.Segments) != 1 || rich.Segments[0].AsMentioner() == nil {
+		t.Fatalf("Unexpected segments %#v", rich.Segments)
+	}
+
+	if start, end := rich.Segments[0].Bounds(); start != 5 || end != len(rich.Content)-1 {
+		t.Errorf("Unexpected bounds [%d %d]", start, end)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	const in = "```go\nfunc main() {}```"
+
+	if segs := parse([]byte(in)).Segments; len(segs) != 1 {
+		t.Fatalf("Expected no highlighting without a style, got %d segments", len(segs))
+	}
+
+	var tests = []struct {
+		name    string
+		in      string
+		keyword string
+	}{
+		{"lf", in, "func"},
+		// CRLFs must not shift the segments of the tokens after them.
+		{"crlf", "```go\nvar a = 1\r\nfunc main() {}```", "func"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var rich text.Rich
+			ParseWithMessageRich(&rich, []byte(test.in), &discord.Message{}, store.NoopCabinet, "monokai")
+
+			var keyword bool
+			for _, seg := range rich.Segments {
+				start, end := seg.Bounds()
+				if start < 4 || end > len(rich.Content)-4 {
+					t.Errorf("Segment [%d %d] outside of the code block", start, end)
+				}
+
+				if rich.Content[start:end] == test.keyword && seg.AsColorer() != nil {
+					keyword = true
+				}
+			}
+
+			if !keyword {
+				t.Fatalf("Keyword not highlighted: %#v", rich.Segments)
+			}
+		})
+	}
+}
+
+func TestHighlightInlineField(t *testing.T) {
+	var msg = discord.Message{
+		Embeds: []discord.Embed{{
+			Fields: []discord.EmbedField{
+				{Name: "Code", Value: "```go\nfunc main() {}```", Inline: true},
+				{Name: "Status", Value: "up", Inline: true},
+			},
+		}},
+	}
+
+	var rich text.Rich
+	ParseMessageRich(&rich, &msg, store.NoopCabinet, "monokai")
+
+	for _, seg := range rich.Segments {
+		start, end := seg.Bounds()
+		if rich.Content[start:end] == "func" && seg.AsColorer() != nil {
+			return
+		}
+	}
+
+	t.Fatalf("Keyword not highlighted in %q: %#v", rich.Content, rich.Segments)
+}
+
+func TestEmbedFields(t *testing.T) {
+	var msg = discord.Message{
+		Embeds: []discord.Embed{{
+			Fields: []discord.EmbedField{
+				{Name: "Status", Value: "**up**", Inline: true},
+				{Name: "Region", Value: "eu\nus", Inline: true},
+				{Name: "Docs", Value: "https://example.com"},
+			},
+		}},
+	}
+
+	text := ParseMessage(&msg, store.NoopCabinet)
+	log.Printf("Output: %#v\n", text)
+
+	const expect = "" +
+		"---\n\n" +
+		"Status   Region\n" +
+		"up       eu\n" +
+		"         us\n" +
+		"Docs\n" +
+		"https://example.com\n" +
+		"---"
+
+	if text.Content != expect {
+		t.Fatalf("Expected %q, got %q", expect, text.Content)
+	}
+
+	var bounds = []struct {
+		start, end int
+		link       bool
+	}{
+		{5, 11, false},  // Status
+		{14, 20, false}, // Region
+		{21, 23, false}, // up
+		{45, 49, false}, // Docs
+		{50, 69, true},  // link
+	}
+
+	if len(text.Segments) != len(bounds) {
+		t.Fatalf("Expected %d segments, got %d", len(bounds), len(text.Segments))
+	}
+
+	for i, seg := range text.Segments {
+		if start, end := seg.Bounds(); start != bounds[i].start || end != bounds[i].end {
+			t.Errorf("Segment %d: expected bounds %v, got [%d %d]", i, bounds[i], start, end)
+		}
+
+		if (seg.AsLinker() != nil) != bounds[i].link {
+			t.Errorf("Segment %d: unexpected linker", i)
+		}
+	}
+
+	// Wide characters take up two cells, so they're padded less.
+	msg.Embeds[0].Fields = []discord.EmbedField{
+		{Name: "言語", Value: "Go", Inline: true},
+		{Name: "Status", Value: "up", Inline: true},
+	}
+
+	text = ParseMessage(&msg, store.NoopCabinet)
+
+	const expectWide = "" +
+		"---\n\n" +
+		"言語   Status\n" +
+		"Go     up\n" +
+		"---"
+
+	if text.Content != expectWide {
+		t.Fatalf("Expected %q, got %q", expectWide, text.Content)
+	}
 }
 
 type mockStore struct {
-	state.NoopStore
+	store.NoopStore
 }
 
-func (mockStore) Channel(id discord.Snowflake) (*discord.Channel, error) {
+func (mockStore) Channel(id discord.ChannelID) (*discord.Channel, error) {
 	if id != 2 {
 		return nil, errors.New("Unknown channel")
 	}
@@ -0,0 +1,68 @@
+package reaction
+
+import (
+	"strconv"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/cchat-discord/internal/segments/colored"
+	"github.com/diamondburned/cchat-discord/internal/segments/emoji"
+	"github.com/diamondburned/cchat-discord/internal/segments/inline"
+	"github.com/diamondburned/cchat-discord/internal/segments/renderer"
+	"github.com/diamondburned/cchat/text"
+)
+
+// Label returns the text label of the given reaction emoji. Custom emojis are
+// formatted as ":name:", while Unicode emojis are returned as-is.
+func Label(e discord.Emoji) string {
+	if e.ID.IsValid() {
+		return ":" + e.Name + ":"
+	}
+	return e.Name
+}
+
+// RenderReactions renders the given list of reactions into a single line. The
+// reactions that the current user has reacted with are highlighted.
+func RenderReactions(r *renderer.Text, reactions []discord.Reaction) {
+	// Don't do anything if there are no reactions.
+	if len(reactions) == 0 {
+		return
+	}
+
+	// Reactions always go into their own line.
+	r.EnsureBreak()
+
+	for i, reaction := range reactions {
+		if i > 0 {
+			r.Buffer.WriteString("  ")
+		}
+
+		RenderReaction(r, reaction)
+	}
+}
+
+// RenderReaction renders a single reaction with its count.
+func RenderReaction(r *renderer.Text, reaction discord.Reaction) {
+	start := r.Buffer.Len()
+
+	// Custom emojis are rendered as images, while Unicode emojis are written
+	// directly into the buffer.
+	if reaction.Emoji.ID.IsValid() {
+		r.Append(emoji.Segment{
+			Start: start,
+			Emoji: emoji.EmojiFromDiscord(reaction.Emoji, false),
+		})
+	} else {
+		r.Buffer.WriteString(reaction.Emoji.Name)
+	}
+
+	_, end := r.WriteString(" " + strconv.Itoa(reaction.Count))
+
+	if reaction.Me {
+		r.Append(
+			colored.NewBlurple(start, end),
+			inline.NewSegment(start, end, text.AttributeBold),
+		)
+	} else {
+		r.Append(inline.NewSegment(start, end, text.AttributeDimmed))
+	}
+}
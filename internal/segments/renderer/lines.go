@@ -0,0 +1,150 @@
+package renderer
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// Line is a line of inline nodes in a paragraph.
+type Line struct {
+	Parent ast.Node
+	Nodes  []ast.Node
+	// Source is the text of the line in the Markdown source.
+	Source []byte
+}
+
+// ParagraphLines returns the lines of all paragraphs in the tree. Lines that
+// don't start with text are skipped, since they can't have a prefix.
+func ParagraphLines(doc ast.Node, src []byte) []Line {
+	var lines []Line
+
+	ast.Walk(doc, func(n ast.Node, enter bool) (ast.WalkStatus, error) {
+		if para, ok := n.(*ast.Paragraph); ok && enter {
+			lines = appendLines(lines, para, src)
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	return lines
+}
+
+func appendLines(lines []Line, para ast.Node, src []byte) []Line {
+	var line []ast.Node
+
+	for n := para.FirstChild(); n != nil; n = n.NextSibling() {
+		// Lines that were already wrapped are blocks of their own.
+		if n.Type() == ast.TypeBlock {
+			if len(line) > 0 {
+				lines = appendLine(lines, para, line, src)
+				line = nil
+			}
+			continue
+		}
+
+		line = append(line, n)
+
+		if t, ok := n.(*ast.Text); ok && (t.SoftLineBreak() || t.HardLineBreak()) {
+			lines = appendLine(lines, para, line, src)
+			line = nil
+		}
+	}
+
+	if len(line) > 0 {
+		lines = appendLine(lines, para, line, src)
+	}
+
+	return lines
+}
+
+func appendLine(lines []Line, para ast.Node, nodes []ast.Node, src []byte) []Line {
+	first, ok := nodes[0].(*ast.Text)
+	if !ok {
+		return lines
+	}
+
+	source := src[first.Segment.Start:]
+	if i := bytes.IndexByte(source, '\n'); i > -1 {
+		source = source[:i]
+	}
+
+	return append(lines, Line{
+		Parent: para,
+		Nodes:  nodes,
+		Source: source,
+	})
+}
+
+// MatchPrefix matches the regular expression against the start of the line,
+// then trims the match off the line if it only spans text nodes. It returns
+// the submatches, or nil if there's no match.
+func (l Line) MatchPrefix(prefix *regexp.Regexp) [][]byte {
+	loc := prefix.FindSubmatchIndex(l.Source)
+	if loc == nil || loc[0] != 0 {
+		return nil
+	}
+
+	// Ignore lines that only have the prefix.
+	if loc[1] >= len(bytes.TrimRight(l.Source, " ")) {
+		return nil
+	}
+
+	// Make sure that the prefix is made only of text.
+	var length int
+	for _, n := range l.Nodes {
+		t, ok := n.(*ast.Text)
+		if !ok {
+			return nil
+		}
+		if length += t.Segment.Len(); length >= loc[1] {
+			break
+		}
+	}
+
+	matches := make([][]byte, len(loc)/2)
+	for i := range matches {
+		if loc[i*2] >= 0 {
+			matches[i] = l.Source[loc[i*2]:loc[i*2+1]]
+		}
+	}
+
+	var trim = loc[1]
+	for _, n := range l.Nodes {
+		if trim == 0 {
+			break
+		}
+
+		t := n.(*ast.Text)
+		if size := t.Segment.Len(); size < trim {
+			trim -= size
+			t.Segment = t.Segment.WithStart(t.Segment.Stop)
+		} else {
+			t.Segment = t.Segment.WithStart(t.Segment.Start + trim)
+			trim = 0
+		}
+	}
+
+	return matches
+}
+
+// Wrap moves the nodes of the line into the container, which then takes the
+// place of the line. The container should be a block node.
+func (l Line) Wrap(container ast.Node) {
+	l.Parent.InsertBefore(l.Parent, l.Nodes[0], container)
+
+	for _, n := range l.Nodes {
+		l.Parent.RemoveChild(l.Parent, n)
+		container.AppendChild(container, n)
+	}
+}
+
+// RenderChildren renders the children of the node. Renderers of nodes that
+// wrap lines use this on enter and skip the children, since nodes in
+// blockquotes are only rendered on enter.
+func (r *Text) RenderChildren(n ast.Node) {
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		ast.Walk(child, r.RenderNode)
+	}
+}
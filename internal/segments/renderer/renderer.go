@@ -22,16 +22,33 @@ func Register(kind ast.NodeKind, r Renderer) {
 
 var smallRenderers = map[ast.NodeKind]Renderer{}
 
+// Transformer changes the parsed Markdown tree of the given source before it
+// is rendered.
+type Transformer func(doc ast.Node, src []byte)
+
+var transformers []Transformer
+
+// RegisterTransformer registers a transformer, which is applied to every tree
+// before it is walked.
+func RegisterTransformer(t Transformer) {
+	transformers = append(transformers, t)
+}
+
 type Text struct {
 	Buffer   *bytes.Buffer
 	Source   []byte
 	Segments []text.Segment
 	Inlines  InlineState
 	Links    LinkState
+	Spoilers SpoilerState
 
 	// these fields can be nil
 	Message *discord.Message
 	Store   store.Cabinet
+
+	// CodeStyle is the name of the style to highlight code blocks with. Code
+	// blocks aren't highlighted if it's empty.
+	CodeStyle string
 }
 
 func New(src []byte) *Text {
@@ -125,6 +142,20 @@ func (r *Text) StartBlockN(n int) {
 	}
 }
 
+// Indent is the indentation of each nesting level of indented blocks.
+const Indent = "    "
+
+// StartIndentedBlockN starts a block like StartBlockN, then indents it by the
+// given nesting level.
+func (r *Text) StartIndentedBlockN(n, level int) {
+	r.StartBlockN(n)
+
+	r.Buffer.Grow(len(Indent) * level)
+	for i := 0; i < level; i++ {
+		r.Buffer.WriteString(Indent)
+	}
+}
+
 func (r *Text) EndBlock() {
 	// Do the same thing as starting a block.
 	r.StartBlock()
@@ -162,6 +193,10 @@ func (r *Text) Walk(n ast.Node) {
 		r.Segments = make([]text.Segment, 0, n.ChildCount())
 	}
 
+	for _, transform := range transformers {
+		transform(n, r.Source)
+	}
+
 	ast.Walk(n, r.RenderNode)
 }
 
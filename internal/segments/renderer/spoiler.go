@@ -0,0 +1,21 @@
+package renderer
+
+// SpoilerState is used for spoiler segments.
+type SpoilerState struct {
+	Spoilerstack []int // stack of starting integers
+}
+
+func (ss *SpoilerState) Append(s int) {
+	ss.Spoilerstack = append(ss.Spoilerstack, s)
+}
+
+func (ss *SpoilerState) Pop() int {
+	ilast := len(ss.Spoilerstack) - 1
+	start := ss.Spoilerstack[ilast]
+	ss.Spoilerstack = ss.Spoilerstack[:ilast]
+	return start
+}
+
+func (ss SpoilerState) Len() int {
+	return len(ss.Spoilerstack)
+}
@@ -0,0 +1,81 @@
+// Package spoiler renders spoilers, which hide their content until revealed.
+package spoiler
+
+import (
+	"strings"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/cchat-discord/internal/segments/renderer"
+	"github.com/diamondburned/cchat/text"
+	"github.com/diamondburned/cchat/utils/empty"
+)
+
+// Delimiter is the Markdown delimiter around spoilers.
+const Delimiter = "||"
+
+// AttachmentPrefix is the filename prefix of spoiler attachments.
+const AttachmentPrefix = "SPOILER_"
+
+// Attributes are the text attributes of spoilers. Frontends that can't hide
+// text until it's revealed dim it instead.
+const Attributes = text.AttributeSpoiler | text.AttributeDimmed
+
+// Render opens a spoiler on enter and closes it otherwise. The inline renderer
+// calls this for spoiler nodes.
+func Render(r *renderer.Text, enter bool) {
+	if enter {
+		r.Spoilers.Append(r.Buffer.Len())
+		return
+	}
+
+	// This shouldn't happen.
+	if r.Spoilers.Len() == 0 {
+		return
+	}
+
+	start := r.Spoilers.Pop()
+	if start < r.Buffer.Len() {
+		r.Append(NewSegment(start, r.Buffer.Len()))
+	}
+}
+
+// IsAttachment returns true if the attachment is marked as a spoiler.
+func IsAttachment(a discord.Attachment) bool {
+	return strings.HasPrefix(a.Filename, AttachmentPrefix)
+}
+
+// AttachmentName returns the filename of the attachment without the spoiler
+// prefix.
+func AttachmentName(a discord.Attachment) string {
+	return strings.TrimPrefix(a.Filename, AttachmentPrefix)
+}
+
+type Attribute text.Attribute
+
+var _ text.Attributor = (*Attribute)(nil)
+
+func (attr Attribute) Attribute() text.Attribute {
+	return text.Attribute(attr)
+}
+
+type Segment struct {
+	empty.TextSegment
+	start, end int
+}
+
+var _ text.Segment = (*Segment)(nil)
+
+func NewSegment(start, end int) Segment {
+	return Segment{
+		start: start,
+		end:   end,
+	}
+}
+
+func (s Segment) Bounds() (start, end int) {
+	return s.start, s.end
+}
+
+func (s Segment) AsAttributor() text.Attributor {
+	return Attribute(Attributes)
+}
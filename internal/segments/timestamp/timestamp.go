@@ -0,0 +1,197 @@
+// Package timestamp renders Discord timestamps, such as <t:1600000000:R>, in
+// local time.
+package timestamp
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/diamondburned/cchat-discord/internal/segments/renderer"
+	"github.com/diamondburned/cchat/text"
+	"github.com/diamondburned/cchat/utils/empty"
+	"github.com/diamondburned/ningen/v2/md"
+	"github.com/dustin/go-humanize"
+	"github.com/yuin/goldmark/ast"
+	gmtext "github.com/yuin/goldmark/text"
+)
+
+func init() {
+	renderer.Register(KindTimestamp, timestamp)
+	renderer.RegisterTransformer(Transform)
+}
+
+var timestampRegex = regexp.MustCompile(`<t:(-?\d{1,13})(?::([tTdDfFR]))?>`)
+
+// Styles maps the timestamp styles to their time layouts. The relative style
+// has none.
+var Styles = map[byte]string{
+	't': "3:04 PM",
+	'T': "3:04:05 PM",
+	'd': "01/02/2006",
+	'D': "January 2, 2006",
+	'f': "January 2, 2006 3:04 PM",
+	'F': "Monday, January 2, 2006 3:04 PM",
+}
+
+// DefaultStyle is the style of timestamps that don't have one.
+const DefaultStyle = 'f'
+
+// RelativeStyle is the style of timestamps relative to the current time.
+const RelativeStyle = 'R'
+
+// Format formats the time in the given style in local time. Relative
+// timestamps are relative to now.
+func Format(t time.Time, style byte, now time.Time) string {
+	if style == RelativeStyle {
+		return humanize.RelTime(t, now, "ago", "from now")
+	}
+
+	layout, ok := Styles[style]
+	if !ok {
+		layout = Styles[DefaultStyle]
+	}
+
+	return t.Local().Format(layout)
+}
+
+// HasRelative returns true if the Markdown source has relative timestamps.
+func HasRelative(src string) bool {
+	for _, match := range timestampRegex.FindAllStringSubmatch(src, -1) {
+		if match[2] == string(RelativeStyle) {
+			return true
+		}
+	}
+	return false
+}
+
+type Timestamp struct {
+	ast.BaseInline
+	Time  time.Time
+	Style byte
+}
+
+var KindTimestamp = ast.NewNodeKind("Timestamp")
+
+// Kind implements Node.Kind.
+func (t *Timestamp) Kind() ast.NodeKind {
+	return KindTimestamp
+}
+
+// Dump implements Node.Dump.
+func (t *Timestamp) Dump(source []byte, level int) {
+	ast.DumpHelper(t, source, level, map[string]string{
+		"Time":  t.Time.String(),
+		"Style": string(t.Style),
+	}, nil)
+}
+
+// Transform splits the timestamps out of the text nodes, since the Markdown
+// parser leaves them as text. Timestamps in inline code are left alone.
+func Transform(doc ast.Node, src []byte) {
+	var texts []*ast.Text
+
+	ast.Walk(doc, func(n ast.Node, enter bool) (ast.WalkStatus, error) {
+		switch n := n.(type) {
+		case *md.Inline:
+			if enter && n.Attr.Has(md.AttrMonospace) {
+				return ast.WalkSkipChildren, nil
+			}
+		case *ast.Text:
+			if enter {
+				texts = append(texts, n)
+			}
+		}
+
+		return ast.WalkContinue, nil
+	})
+
+	for _, n := range texts {
+		split(n, src)
+	}
+}
+
+// split inserts the timestamps in the text node and the text before them as
+// its previous siblings. The node is left with the text after the last one.
+func split(n *ast.Text, src []byte) {
+	value := n.Segment.Value(src)
+	start := n.Segment.Start
+
+	matches := timestampRegex.FindAllSubmatchIndex(value, -1)
+	if len(matches) == 0 {
+		return
+	}
+
+	parent := n.Parent()
+	var last int
+
+	for _, match := range matches {
+		sec, err := strconv.ParseInt(string(value[match[2]:match[3]]), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		var style byte = DefaultStyle
+		if match[4] >= 0 {
+			style = value[match[4]]
+		}
+
+		if last < match[0] {
+			before := gmtext.NewSegment(start+last, start+match[0])
+			parent.InsertBefore(parent, n, ast.NewTextSegment(before))
+		}
+
+		parent.InsertBefore(parent, n, &Timestamp{
+			Time:  time.Unix(sec, 0),
+			Style: style,
+		})
+
+		last = match[1]
+	}
+
+	n.Segment = n.Segment.WithStart(start + last)
+}
+
+func timestamp(r *renderer.Text, node ast.Node, enter bool) ast.WalkStatus {
+	n := node.(*Timestamp)
+
+	if enter {
+		start, end := r.WriteString(Format(n.Time, n.Style, time.Now()))
+		r.Append(NewSegment(start, end, n.Time))
+	}
+
+	return ast.WalkContinue
+}
+
+// Segment is a timestamp that shows the full date when clicked.
+type Segment struct {
+	empty.TextSegment
+	start, end int
+	time       time.Time
+}
+
+var (
+	_ text.Segment   = (*Segment)(nil)
+	_ text.Mentioner = (*Segment)(nil)
+)
+
+func NewSegment(start, end int, t time.Time) Segment {
+	return Segment{
+		start: start,
+		end:   end,
+		time:  t,
+	}
+}
+
+func (s Segment) Bounds() (start, end int) {
+	return s.start, s.end
+}
+
+func (s Segment) AsMentioner() text.Mentioner {
+	return s
+}
+
+// MentionInfo returns the full date of the timestamp.
+func (s Segment) MentionInfo() text.Rich {
+	return text.Plain(Format(s.time, 'F', time.Now()))
+}